@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"html/template"
 	"os"
@@ -10,6 +11,10 @@ import (
 	"strings"
 	"time"
 
+	htmlgenerator "commit-insights/internal/generators"
+	"commit-insights/internal/payloads"
+	"commit-insights/internal/report"
+
 	"github.com/joho/godotenv"
 	"github.com/vanng822/go-premailer/premailer"
 )
@@ -70,6 +75,12 @@ const htmlStyle = `
 		.orange {
 			background-color: rgba(255, 165, 0, 0.3);
 		}
+		.blue {
+			background-color: rgba(0, 171, 227, 0.3);
+		}
+		.purple {
+			background-color: rgba(111, 66, 193, 0.3);
+		}
 
 		table {
 			width: 100%;
@@ -107,6 +118,12 @@ const htmlBody = `
 	<div class="section">
 		<strong>Committers:</strong> {{.Committers}}
 	</div>
+	<div class="section">
+		<strong>Files Changed:</strong> {{.FilesChanged}}<br>
+		<strong>Lines Added:</strong> {{.Additions}}<br>
+		<strong>Lines Removed:</strong> {{.Deletions}}{{if .BinaryFiles}}<br>
+		<strong>Binary Files:</strong> {{.BinaryFiles}}{{end}}
+	</div>
 	<div class="section">
 		<strong>Pipeline Name:</strong> {{.PipeName}}<br>
 		<strong>Pipeline Build Started:</strong> {{.PipeBuildCreated}}<br>
@@ -119,6 +136,8 @@ const htmlBody = `
 				<th>Committer/Reviewer</th>
 				<th>Status</th>
 				<th>File Name</th>
+				<th>Added</th>
+				<th>Removed</th>
 				<th>Commit Hash</th>
 				<th>Title</th>
 				<th>Date</th>
@@ -126,15 +145,75 @@ const htmlBody = `
 			{{range .FileChanges}}
 			<tr class="{{.StatusClass}}">
 				<td>{{.Committer}}{{if .Reviewer}} / {{.Reviewer}}{{end}}</td>
-				<td>{{.Status}}</td>
-				<td>{{.FileName}}</td>
+				<td>{{.Status}}{{if .Similarity}} ({{.Similarity}}%){{end}}</td>
+				<td>{{if .OldPath}}{{.OldPath}} &rarr; {{end}}{{.FileName}}</td>
+				{{if .Binary}}<td colspan="2">binary</td>{{else}}<td>+{{.Additions}}</td>
+				<td>-{{.Deletions}}</td>{{end}}
 				<td>{{.CommitHash}}</td>
 				<td>{{.Title}}</td>
 				<td>{{.Time}}</td>
 			</tr>
 			{{end}}
+			<tr>
+				<th colspan="3">Total</th>
+				<th>+{{.Additions}}</th>
+				<th>-{{.Deletions}}</th>
+				<th colspan="3"></th>
+			</tr>
+		</table>
+	</div>
+	{{if .MergedPRs}}
+	<div class="section">
+		<strong>Merged PRs:</strong><p>
+		<table>
+			<tr>
+				<th>Merge</th>
+				<th>Merged By</th>
+				<th>Commit Hash</th>
+				<th>Date</th>
+				<th>Commits</th>
+			</tr>
+			{{range .MergedPRs}}
+			<tr>
+				<td>{{.Title}}</td>
+				<td>{{.Author}}</td>
+				<td>{{.Hash}}</td>
+				<td>{{.Time}}</td>
+				<td>{{range .Commits}}{{.ShortHash}} {{.Title}} ({{.Author.Name}})<br>{{else}}-{{end}}</td>
+			</tr>
+			{{end}}
+		</table>
+	</div>
+	{{end}}
+	{{if .RefUpdates}}
+	<div class="section">
+		<strong>Ref Updates:</strong><p>
+		<table>
+			<tr>
+				<th>Ref</th>
+				<th>Type</th>
+				<th>Action</th>
+				<th>Old Commit</th>
+				<th>New Commit</th>
+			</tr>
+			{{range .RefUpdates}}
+			<tr class="{{.StatusClass}}">
+				<td>{{.Name}}</td>
+				<td>{{.Type}}</td>
+				<td>{{.Action}}</td>
+				<td>{{if .OldSHA}}{{.OldSHA}}{{else}}-{{end}}</td>
+				<td>{{if .NewSHA}}{{.NewSHA}}{{else}}-{{end}}</td>
+			</tr>
+			{{end}}
 		</table>
 	</div>
+	{{end}}
+	{{if .Dashboard}}
+	<div class="section">
+		<strong>Pipeline Dashboard:</strong><p>
+		{{.Dashboard}}
+	</div>
+	{{end}}
 </div>
 `
 const htmlPostBody = `
@@ -142,8 +221,10 @@ const htmlPostBody = `
 </html>
 `
 
-const htmlTemplate = htmlHeader + htmlStyle + htmlPreBody + htmlBody + htmlPostBody
+const htmlTemplate = htmlHeader + htmlStyle + htmlgenerator.DashboardStyle + htmlPreBody + htmlBody + htmlPostBody
 
+// reportData is what the report templates are executed with, see the Data
+// Model section of the README.
 type reportData struct {
 	RepoName         string
 	BranchName       string
@@ -153,179 +234,111 @@ type reportData struct {
 	PipeName         string
 	PipeURL          string
 	PipeBuildCreated string
-	FileChanges      []struct {
-		FileName    string
-		Status      string
-		StatusClass string
-		Committer   string
-		Reviewer    string
-		CommitHash  string
-		Title       string
-		Time        string
-	}
+	FilesChanged     int
+	Additions        int
+	Deletions        int
+	BinaryFiles      int
+	FileChanges      []fileChangeRow
+	MergedPRs        []mergedPRRow
+	RefUpdates       []refUpdateRow
+	Releases         []string
+	Dashboard        template.HTML
+	// Report is the report model, and Insights its JSON document, for custom
+	// templates.
+	Report   *report.Report
+	Insights *Insights
 }
 
-func GenerateReport(repoName string, branchName string, triggerType string, committers []string, commitersEmail []string, pipeName string, pipeURL string, fileChanges []struct {
-	FileName   template.HTML
-	Status     string
-	Committer  string
-	Reviewer   string
-	CommitHash string
-	Title      string
-	Time       string
-}, buildCreated string) (string, error) {
-	var committersStr string
-	if len(committers) > 0 {
-		committersStr = strings.Join(committers, ", ")
-	}
-	var committersEmailStr string
-	if len(commitersEmail) > 0 {
-		committersEmailStr = strings.Join(commitersEmail, ", ")
-	}
+// fileChangeRow is a row of the file changes table.
+type fileChangeRow struct {
+	FileName    string
+	Status      string
+	StatusClass string
+	Committer   string
+	Reviewer    string
+	CommitHash  string
+	Title       string
+	Time        string
+	Additions   int
+	Deletions   int
+	Binary      bool
+	OldPath     string
+	Similarity  int
+}
 
-	var fileChangesData []struct {
-		FileName    template.HTML
-		Status      string
-		StatusClass string
-		Committer   string
-		Reviewer    string
-		CommitHash  string
-		Title       string
-		Time        time.Time
-	}
-	for _, change := range fileChanges {
-		var statusText, statusClass string
-		switch change.Status {
-		case "A":
-			statusText = "Added"
-			statusClass = "green"
-		case "M":
-			statusText = "Modified"
-			statusClass = "orange"
-		case "D":
-			statusText = "Deleted"
-			statusClass = "red"
-		default:
-			statusText = change.Status
-		}
-		//convert epoch 1694299436 format to human readable format
-		// timeInt, err := strconv.Atoi(change.Time)
-		// if err != nil {
-		// 	return "", err
-		// }
-		// currentTimezone := time.Now().Format("-0700")
-		// // fmt.Println(currentTimezone)
-
-		// date := time.Unix(int64(timeInt), 0).Format("2006-01-02 15:04:05") + " " + currentTimezone
-		// _ = date // fix "declared and not used" error
-		timeInt, err := strconv.Atoi(change.Time)
-		if err != nil {
-			return "", err
-		}
-		date := time.Unix(int64(timeInt), 0)
-		_, offsetSeconds := time.Now().Zone()
-		offset := time.Duration(offsetSeconds) * time.Second
-		date = date.Add(offset)
-
-		fileChangesData = append(fileChangesData, struct {
-			FileName    template.HTML
-			Status      string
-			StatusClass string
-			Committer   string
-			Reviewer    string
-			CommitHash  string
-			Title       string
-			Time        time.Time
-		}{
-			FileName:    change.FileName,
-			Status:      statusText,
-			StatusClass: statusClass,
-			Committer:   change.Committer,
-			Reviewer:    change.Reviewer,
-			CommitHash:  change.CommitHash,
-			Title:       change.Title,
-			Time:        date,
-		})
-	}
+// mergedPRRow is a row of the merged PRs table.
+type mergedPRRow struct {
+	Hash    string
+	Title   string
+	Author  string
+	Time    string
+	Commits []report.CommitEntry
+}
 
-	sort.Slice(fileChangesData, func(i, j int) bool {
-		return fileChangesData[i].Time.After(fileChangesData[j].Time)
-	})
+// refUpdateRow is a row of the ref updates table.
+type refUpdateRow struct {
+	Name        string
+	Type        string
+	Action      string
+	StatusClass string
+	OldSHA      string
+	NewSHA      string
+}
 
-	data := reportData{
-		RepoName:         repoName,
-		BranchName:       branchName,
-		TriggerType:      triggerType,
-		Committers:       committersStr,
-		CommittersEmail:  committersEmailStr,
-		PipeName:         pipeName,
-		PipeURL:          pipeURL,
-		PipeBuildCreated: buildCreated,
-		FileChanges: func() []struct {
-			FileName    string
-			Status      string
-			StatusClass string
-			Committer   string
-			Reviewer    string
-			CommitHash  string
-			Title       string
-			Time        string
-		} {
-			var changes []struct {
-				FileName    string
-				Status      string
-				StatusClass string
-				Committer   string
-				Reviewer    string
-				CommitHash  string
-				Title       string
-				Time        string
-			}
-			for _, change := range fileChangesData {
-				changes = append(changes, struct {
-					FileName    string
-					Status      string
-					StatusClass string
-					Committer   string
-					Reviewer    string
-					CommitHash  string
-					Title       string
-					Time        string
-				}{
-					FileName:    string(change.FileName),
-					Status:      change.Status,
-					StatusClass: change.StatusClass,
-					Committer:   change.Committer,
-					Reviewer:    change.Reviewer,
-					CommitHash:  change.CommitHash,    // Added this line
-					Title:       change.Title,         // Added this line
-					Time:        change.Time.String(), // Updated this line
-				})
-			}
-			return changes
-		}(),
-	}
+// GenerateReport renders the HTML report with the given template, or the
+// built-in one when tmpl is nil or fails, with its CSS inlined for emails.
+func GenerateReport(r *report.Report, tmpl *ReportTemplate) (string, error) {
+	_, inlinedHtml, err := renderHTMLReport(r, tmpl)
+	return inlinedHtml, err
+}
 
-	tmpl, err := template.New("report").Parse(htmlTemplate)
-	if err != nil {
-		return "", err
-	}
+// renderHTMLReport renders the HTML report, returning it as rendered and with
+// its CSS inlined.
+func renderHTMLReport(r *report.Report, tmpl *ReportTemplate) (string, string, error) {
+	data := newReportData(r)
 
 	var report strings.Builder
-	if err := tmpl.Execute(&report, data); err != nil {
-		return "", err
+	var err error
+	if tmpl != nil {
+		err = tmpl.Execute(&report, data)
+		if err != nil {
+			fmt.Printf("| \033[33m[WARNING] - Failed to render the template %s, using the built-in template: %v\033[0m\n", tmpl.Path, err)
+			report.Reset()
+		}
+	}
+	if tmpl == nil || err != nil {
+		builtin, err := template.New("report").Parse(htmlTemplate)
+		if err != nil {
+			return "", "", err
+		}
+		if err := builtin.Execute(&report, data); err != nil {
+			return "", "", err
+		}
 	}
 
 	p, err := premailer.NewPremailerFromString(report.String(), premailer.NewOptions())
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
 	inlinedHtml, err := p.Transform()
 	if err != nil {
 		fmt.Println("Error inlining CSS:", err)
-		return "", err
+		return "", "", err
+	}
+	return report.String(), inlinedHtml, nil
+}
+
+// ExportOutputs exports the values of the report to DRONE_OUTPUT for the
+// following steps, whatever the output formats. The REPORT variables hold the
+// HTML report rendered with the given template.
+func ExportOutputs(r *report.Report, tmpl *ReportTemplate) error {
+	data := newReportData(r)
+	html, inlinedHtml, err := renderHTMLReport(r, tmpl)
+	if err != nil {
+		return err
 	}
+
 	// 1. Calculate the length and the breakpoints for the inlined HTML string
 	totalLength := len(inlinedHtml)
 	partLength := totalLength / 3
@@ -345,16 +358,22 @@ func GenerateReport(repoName string, branchName string, triggerType string, comm
 		"HTML_PREBODY":       htmlPreBody,
 		"HTML_BODY":          htmlBody,
 		"HTML_POSTBODY":      htmlPostBody,
-		"REPO_NAME":          repoName,
-		"BRANCH_NAME":        branchName,
-		"TRIGGER_TYPE":       triggerType,
-		"COMMITTERS":         committersStr,
-		"COMMITTERS_EMAIL":   committersEmailStr,
-		"PIPE_NAME":          pipeName,
-		"PIPE_URL":           pipeURL,
-		"PIPE_BUILD_CREATED": buildCreated,
-		"FILE_CHANGES":       fmt.Sprintf("%v", fileChanges),
-		"REPORT":             strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(report.String(), "\n", ""), "	", ""), "		", ""), "<!DOCTYPE html>", ""),
+		"REPO_NAME":          data.RepoName,
+		"BRANCH_NAME":        data.BranchName,
+		"TRIGGER_TYPE":       data.TriggerType,
+		"COMMITTERS":         data.Committers,
+		"COMMITTERS_EMAIL":   data.CommittersEmail,
+		"PIPE_NAME":          data.PipeName,
+		"PIPE_URL":           data.PipeURL,
+		"PIPE_BUILD_CREATED": data.PipeBuildCreated,
+		"FILE_CHANGES":       fileChangesOutput(r),
+		"FILES_CHANGED":      strconv.Itoa(data.FilesChanged),
+		"LINES_ADDED":        strconv.Itoa(data.Additions),
+		"LINES_REMOVED":      strconv.Itoa(data.Deletions),
+		"BINARY_FILES":       strconv.Itoa(data.BinaryFiles),
+		"MERGED_PRS":         strconv.Itoa(len(data.MergedPRs)),
+		"RELEASES":           strings.Join(data.Releases, ","),
+		"REPORT":             strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(strings.ReplaceAll(html, "\n", ""), "	", ""), "		", ""), "<!DOCTYPE html>", ""),
 		"REPORT_PART1":       part1,
 		"REPORT_PART2":       part2,
 		"REPORT_PART3":       part3,
@@ -366,7 +385,176 @@ func GenerateReport(repoName string, branchName string, triggerType string, comm
 		fmt.Printf("| \033[33m[WARNING] - Failed to write to .env: %v\033[0m\n", err)
 	}
 
-	return inlinedHtml, nil
+	return nil
+}
+
+// fileChangeOutput is an entry of the FILE_CHANGES output variable.
+type fileChangeOutput struct {
+	Commit string `json:"commit"`
+	InsightsFileChange
+}
+
+// fileChangesOutput encodes the file changes of every commit as a JSON array
+// for the FILE_CHANGES output variable, in the format of the changes of the
+// insights document plus the commit hash.
+func fileChangesOutput(r *report.Report) string {
+	changes := []fileChangeOutput{}
+	for _, commit := range r.Commits {
+		for _, change := range commit.Changes {
+			changes = append(changes, fileChangeOutput{Commit: commit.Hash, InsightsFileChange: insightsFileChange(change)})
+		}
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return "[]"
+	}
+	return string(data)
+}
+
+// newReportData builds the data the report templates are executed with.
+func newReportData(r *report.Report) reportData {
+	var committerNames, committerEmails []string
+	for _, committer := range r.Committers {
+		committerNames = append(committerNames, committer.DisplayName())
+		if committer.Email != "" {
+			committerEmails = append(committerEmails, committer.Email)
+		}
+	}
+
+	stats := r.Stats()
+	data := reportData{
+		RepoName:         r.Repository.Name,
+		BranchName:       r.Repository.Branch,
+		TriggerType:      r.Repository.BuildType,
+		Committers:       strings.Join(committerNames, ", "),
+		CommittersEmail:  strings.Join(committerEmails, ", "),
+		PipeURL:          r.ExecutionURL,
+		PipeBuildCreated: formatBuildCreated(r.BuildCreated),
+		FilesChanged:     stats.FilesChanged,
+		Additions:        stats.Additions,
+		Deletions:        stats.Deletions,
+		BinaryFiles:      stats.BinaryFiles,
+		Releases:         r.Releases(),
+		Dashboard:        r.Dashboard,
+		Report:           r,
+		Insights:         BuildInsights(r),
+	}
+	if r.Baseline != nil {
+		data.PipeName = r.Baseline.Name
+	}
+
+	// Every commit is visited once, so each file change ends up in exactly
+	// one row. Rows are sorted by commit time, newest first.
+	commits := append([]report.CommitEntry(nil), r.Commits...)
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Time.After(commits[j].Time)
+	})
+	for _, commit := range commits {
+		for _, change := range commit.Changes {
+			var statusText, statusClass string
+			switch change.Status {
+			case "A":
+				statusText = "Added"
+				statusClass = "green"
+			case "M":
+				statusText = "Modified"
+				statusClass = "orange"
+			case "D":
+				statusText = "Deleted"
+				statusClass = "red"
+			case "R":
+				statusText = "Renamed"
+				statusClass = "blue"
+			case "C":
+				statusText = "Copied"
+				statusClass = "purple"
+			default:
+				statusText = change.Status
+			}
+
+			data.FileChanges = append(data.FileChanges, fileChangeRow{
+				FileName:    change.Path,
+				Status:      statusText,
+				StatusClass: statusClass,
+				Committer:   strings.Join(reportNames(commit.Credited()), ", "),
+				Reviewer:    strings.Join(reportNames(commit.ReviewedBy), ", "),
+				CommitHash:  commit.Hash,
+				Title:       commit.Title,
+				Time:        reportTime(commit.Time).String(),
+				Additions:   change.Additions,
+				Deletions:   change.Deletions,
+				Binary:      change.Binary,
+				OldPath:     change.OldPath,
+				Similarity:  change.Similarity,
+			})
+		}
+	}
+
+	for _, pr := range r.MergedPRs {
+		data.MergedPRs = append(data.MergedPRs, mergedPRRow{
+			Hash:    pr.Merge.Hash,
+			Title:   pr.Merge.Title,
+			Author:  pr.Merge.Author.Name,
+			Time:    reportTime(pr.Merge.Time).String(),
+			Commits: pr.Commits,
+		})
+	}
+
+	for _, ref := range r.Range.Refs {
+		refType := "Branch"
+		if ref.Release {
+			refType = "Release"
+		} else if ref.Type == payloads.RefTag {
+			refType = "Tag"
+		}
+
+		var statusClass string
+		switch ref.Action {
+		case payloads.ActionCreated:
+			statusClass = "green"
+		case payloads.ActionUpdated:
+			statusClass = "orange"
+		case payloads.ActionDeleted:
+			statusClass = "red"
+		}
+
+		data.RefUpdates = append(data.RefUpdates, refUpdateRow{
+			Name:        ref.Name,
+			Type:        refType,
+			Action:      strings.ToUpper(ref.Action[:1]) + ref.Action[1:],
+			StatusClass: statusClass,
+			OldSHA:      report.ShortHash(ref.OldSHA),
+			NewSHA:      report.ShortHash(ref.NewSHA),
+		})
+	}
+
+	return data
+}
+
+// reportNames returns the names of people, falling back to their email.
+func reportNames(people []report.Person) []string {
+	var names []string
+	for _, person := range people {
+		names = append(names, person.DisplayName())
+	}
+	return names
+}
+
+// reportTime converts a commit time into the report time, shifted by the
+// local timezone offset.
+func reportTime(t time.Time) time.Time {
+	_, offsetSeconds := time.Now().Zone()
+	offset := time.Duration(offsetSeconds) * time.Second
+	return t.Add(offset)
+}
+
+// formatBuildCreated formats the creation time of the build as MM/DD/YYYY
+// HH:MM:SS AM/PM, shifted by the local timezone offset, e.g. 09/22/2021
+// 12:00:00 AM -0300.
+func formatBuildCreated(created time.Time) string {
+	t := reportTime(created.UTC())
+	currentTimezone := time.Now().Format("-0700")
+	return t.Format("01/02/2006 03:04:05 PM " + currentTimezone)
 }
 
 func writeEnvFile(vars map[string]string, outputPath string) error {
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"commit-insights/internal/report"
+
+	"github.com/joho/godotenv"
+)
+
+func TestFileChangesOutput(t *testing.T) {
+	r := &report.Report{
+		Commits: []report.CommitEntry{
+			{
+				Hash: "aaa",
+				Changes: []report.FileChange{
+					{Path: "docs.txt", OldPath: "notes.txt", Status: "R", Similarity: 94, Additions: 1},
+					{Path: "logo.png", Status: "A", Binary: true},
+				},
+			},
+			{Hash: "bbb"},
+		},
+	}
+
+	var got []map[string]interface{}
+	if err := json.Unmarshal([]byte(fileChangesOutput(r)), &got); err != nil {
+		t.Fatalf("FILE_CHANGES is not JSON: %v", err)
+	}
+	want := []map[string]interface{}{
+		{"commit": "aaa", "path": "docs.txt", "oldPath": "notes.txt", "status": "R", "similarity": float64(94), "additions": float64(1), "deletions": float64(0), "binary": false},
+		{"commit": "aaa", "path": "logo.png", "status": "A", "additions": float64(0), "deletions": float64(0), "binary": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if empty := fileChangesOutput(&report.Report{}); empty != "[]" {
+		t.Errorf("got %s without changes, want []", empty)
+	}
+}
+
+func TestExportOutputs(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "outputs", "drone.env")
+	t.Setenv("DRONE_OUTPUT", envFile)
+
+	r := &report.Report{
+		Repository: report.Repository{Name: "commit-insights", Branch: "main", BuildType: "push"},
+		Commits: []report.CommitEntry{{
+			Hash:      "aaa",
+			Title:     "Add logo",
+			Author:    report.Person{Name: "Ada Lovelace", Email: "ada@example.com"},
+			Additions: 1,
+			Changes:   []report.FileChange{{Path: "logo.png", Status: "A", Binary: true}},
+		}},
+	}
+	if err := ExportOutputs(r, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	vars, err := godotenv.Read(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{
+		"REPO_NAME":     "commit-insights",
+		"BRANCH_NAME":   "main",
+		"FILES_CHANGED": "1",
+		"BINARY_FILES":  "1",
+		"FILE_CHANGES":  fileChangesOutput(r),
+	} {
+		if vars[name] != want {
+			t.Errorf("%s = %q, want %q", name, vars[name], want)
+		}
+	}
+	if !strings.Contains(vars["REPORT"], "Add logo") || vars["REPORT_PART1"]+vars["REPORT_PART2"]+vars["REPORT_PART3"] == "" {
+		t.Errorf("REPORT variables without the HTML report: %q", vars["REPORT"])
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Supported values for the git_backend setting.
+const (
+	BackendAuto  = "auto"
+	BackendExec  = "exec"
+	BackendGoGit = "go-git"
+)
+
+// LogOptions selects the commits returned by GitBackend.Log.
+type LogOptions struct {
+	// Old is the oldest commit of the range, it is included in the result
+	// unless ExcludeOld is set.
+	Old string
+	// New is the newest commit of the range.
+	New string
+	// ExcludeOld selects old..new (commits reachable from New but not from
+	// Old) instead of old^..new.
+	ExcludeOld bool
+	// FirstParent only follows the first parent of merge commits.
+	FirstParent bool
+	// MergeDiffs reports the changes of merge commits against their first
+	// parent. By default merge commits have no file changes.
+	MergeDiffs bool
+	// Strategy is the range strategy the range was resolved with, e.g.
+	// merge-base for a pull request built with auto. It is only reported,
+	// backends ignore it.
+	Strategy string
+}
+
+// GitBackend reads commit history from a repository.
+type GitBackend interface {
+	// Name identifies the backend in the logs.
+	Name() string
+	// Log returns the commits in the range, newest first, with their file
+	// changes and line statistics.
+	Log(opts LogOptions) ([]CommitInfo, error)
+	// MergeBase returns the best common ancestor of two revisions.
+	MergeBase(a string, b string) (string, error)
+	// ResolveRevision returns the commit hash of a revision, or an error when
+	// it does not exist in the repository.
+	ResolveRevision(revision string) (string, error)
+}
+
+// ErrBaseCommitUnreachable is returned when the commits of a range are not
+// available in the local repository, even after deepening a shallow clone.
+var ErrBaseCommitUnreachable = errors.New("base commit is not reachable")
+
+// Deepener is implemented by backends that can fetch more history when the
+// local repository is a shallow clone.
+type Deepener interface {
+	// EnsureReachable deepens the clone until every commit needed by the range
+	// is available, fetching at most maxDepth additional commits.
+	EnsureReachable(opts LogOptions, maxDepth int) error
+}
+
+// BranchFetcher is implemented by backends that can fetch a branch that is
+// missing from the local clone, e.g. the target branch of a pull request.
+type BranchFetcher interface {
+	FetchBranch(branch string) error
+}
+
+// NewGitBackend creates the backend selected by name for the repository at
+// path. "auto" uses the git binary when it is installed and falls back to the
+// pure Go implementation otherwise.
+func NewGitBackend(name string, path string) (GitBackend, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", BackendAuto:
+		if _, err := exec.LookPath("git"); err == nil {
+			return NewExecBackend(path)
+		}
+		fmt.Println("| \033[33mGit is not installed on the system, using the go-git backend.\033[0m")
+		return OpenGoGitBackend(path)
+	case BackendExec:
+		return NewExecBackend(path)
+	case BackendGoGit:
+		return OpenGoGitBackend(path)
+	default:
+		return nil, fmt.Errorf("unknown git backend %q, expected one of: %s, %s, %s", name, BackendAuto, BackendExec, BackendGoGit)
+	}
+}
+
+// requiredRevisions returns the revisions that must resolve for the range to
+// be logged: old^..new needs the parent of the oldest commit as well.
+func requiredRevisions(opts LogOptions) []string {
+	switch {
+	case opts.ExcludeOld:
+		return []string{opts.New, opts.Old}
+	case opts.Old == opts.New:
+		return []string{opts.New}
+	default:
+		return []string{opts.New, opts.Old, opts.Old + "^"}
+	}
+}
+
+// splitMessage splits a commit message into its subject and body the same way
+// git's %s and %b placeholders do: the subject is the first paragraph joined
+// into a single line, the body is everything after it.
+func splitMessage(message string) (string, string) {
+	message = strings.TrimLeft(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+
+	subject, body, _ := strings.Cut(message, "\n\n")
+	subject = strings.Join(strings.Fields(strings.ReplaceAll(subject, "\n", " ")), " ")
+	body = strings.Trim(body, "\n")
+
+	return subject, body
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// deepenStep is the number of commits fetched by the first deepening
+// attempt, every following attempt doubles it.
+const deepenStep = 50
+
+// ExecBackend reads the history by running the git binary.
+type ExecBackend struct {
+	dir string
+}
+
+// NewExecBackend checks that git is installed, marks every directory as safe
+// (CI workspaces are often owned by another user) and prints the repository
+// status.
+func NewExecBackend(dir string) (*ExecBackend, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		fmt.Println("| \033[1;31mGit is not installed on the system.\033[0m")
+		return nil, err
+	}
+
+	cmd := exec.Command("git", "config", "--global", "--add", "safe.directory", "*")
+	if err := cmd.Run(); err != nil {
+		fmt.Println("| \033[33mError configuring git safe.directory:\033[0m", err)
+	}
+	fmt.Println("| \033[1;36mGit is installed and configured.\033[0m")
+
+	backend := &ExecBackend{dir: dir}
+
+	statusOut, err := backend.git("status")
+	if err != nil {
+		fmt.Println("| \033[1;31mError checking git status:\033[0m", err)
+		return nil, err
+	}
+	fmt.Println("| \033[1;36mGit Status:\033[0m\n", statusOut)
+
+	return backend, nil
+}
+
+func (b *ExecBackend) Name() string {
+	return BackendExec
+}
+
+func (b *ExecBackend) Log(opts LogOptions) ([]CommitInfo, error) {
+	args := []string{"log", "-z", "--pretty=format:" + gitLogFormat, "--raw", "--numstat", "--find-renames", "--find-copies"}
+	if opts.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if opts.MergeDiffs {
+		// Same diff as -m --first-parent, without limiting the walk to the
+		// first parent.
+		args = append(args, "--diff-merges=first-parent")
+	}
+	switch {
+	case opts.ExcludeOld:
+		args = append(args, opts.Old+".."+opts.New)
+	case opts.Old == opts.New:
+		args = append(args, "--max-count=1", opts.New)
+	default:
+		args = append(args, opts.Old+"^.."+opts.New)
+	}
+
+	out, err := b.git(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	commits, err := parseGitLog(out)
+	if err != nil {
+		fmt.Println("| \033[1;31mError parsing git log:\033[0m", err)
+		return nil, err
+	}
+
+	return commits, nil
+}
+
+func (b *ExecBackend) MergeBase(a string, c string) (string, error) {
+	out, err := b.git("merge-base", a, c)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+func (b *ExecBackend) ResolveRevision(revision string) (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", revision+"^{commit}")
+	cmd.Dir = b.dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", revision)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// FetchBranch fetches a branch from origin into its remote-tracking ref.
+func (b *ExecBackend) FetchBranch(branch string) error {
+	_, err := b.git("fetch", "--no-tags", "origin", "+refs/heads/"+branch+":refs/remotes/origin/"+branch)
+	return err
+}
+
+func (b *ExecBackend) EnsureReachable(opts LogOptions, maxDepth int) error {
+	revisions := requiredRevisions(opts)
+	missing := b.missingRevisions(revisions)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if !b.isShallow() {
+		return fmt.Errorf("%w: %s not found and the repository is not a shallow clone", ErrBaseCommitUnreachable, strings.Join(missing, ", "))
+	}
+	if maxDepth <= 0 {
+		return fmt.Errorf("%w: %s not found in the shallow clone and deepening is disabled", ErrBaseCommitUnreachable, strings.Join(missing, ", "))
+	}
+
+	fmt.Printf("| \033[33mShallow clone detected, %s not found. Deepening up to %d commits...\033[0m\n", strings.Join(missing, ", "), maxDepth)
+
+	fetched := 0
+	for step := deepenStep; fetched < maxDepth; step *= 2 {
+		depth := step
+		if fetched+depth > maxDepth {
+			depth = maxDepth - fetched
+		}
+		if _, err := b.git("fetch", "--no-tags", "--deepen="+strconv.Itoa(depth)); err != nil {
+			return fmt.Errorf("%w: deepening the clone failed: %v", ErrBaseCommitUnreachable, err)
+		}
+		fetched += depth
+
+		missing = b.missingRevisions(revisions)
+		if len(missing) == 0 {
+			fmt.Printf("| \033[1;36mBase commit reached after fetching %d more commits.\033[0m\n", fetched)
+			return nil
+		}
+		if !b.isShallow() {
+			// The whole history has been fetched, deepening further won't help.
+			break
+		}
+	}
+
+	return fmt.Errorf("%w: %s still not found after fetching %d more commits (max_fetch_depth=%d)", ErrBaseCommitUnreachable, strings.Join(missing, ", "), fetched, maxDepth)
+}
+
+// missingRevisions returns the revisions that do not resolve to a commit.
+func (b *ExecBackend) missingRevisions(revisions []string) []string {
+	var missing []string
+	for _, revision := range revisions {
+		if _, err := b.ResolveRevision(revision); err != nil {
+			missing = append(missing, revision)
+		}
+	}
+	return missing
+}
+
+// isShallow reports whether the repository is a shallow clone.
+func (b *ExecBackend) isShallow() bool {
+	cmd := exec.Command("git", "rev-parse", "--is-shallow-repository")
+	cmd.Dir = b.dir
+	out, err := cmd.Output()
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// git runs a git command in the repository and returns its standard output.
+func (b *ExecBackend) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = b.dir
+	fmt.Println("| \033[1;36mCommand:\033[0m " + cmd.String())
+
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Println("| \033[1;31mError executing command:\033[0m", err)
+		fmt.Println("| \033[1;31mGit error details:\033[0m", stderr.String())
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return out.String(), nil
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// GoGitBackend reads the history with go-git, without needing a git binary.
+// go-git has no copy detection, so copies are reported as added files.
+type GoGitBackend struct {
+	repo *gogit.Repository
+}
+
+// OpenGoGitBackend opens the repository containing path.
+func OpenGoGitBackend(path string) (*GoGitBackend, error) {
+	repo, err := gogit.PlainOpenWithOptions(path, &gogit.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		fmt.Println("| \033[1;31mError opening repository:\033[0m", err)
+		return nil, err
+	}
+	return NewGoGitBackend(repo), nil
+}
+
+// NewGoGitBackend wraps an already opened repository, which may use any
+// storage (e.g. memory.NewStorage()).
+func NewGoGitBackend(repo *gogit.Repository) *GoGitBackend {
+	return &GoGitBackend{repo: repo}
+}
+
+func (b *GoGitBackend) Name() string {
+	return BackendGoGit
+}
+
+func (b *GoGitBackend) Log(opts LogOptions) ([]CommitInfo, error) {
+	newCommit, err := b.commit(opts.New)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Old == opts.New && !opts.ExcludeOld {
+		info, err := b.commitInfo(newCommit, b.refNames(), opts.MergeDiffs)
+		if err != nil {
+			return nil, err
+		}
+		return []CommitInfo{info}, nil
+	}
+
+	oldCommit, err := b.commit(opts.Old)
+	if err != nil {
+		return nil, err
+	}
+
+	// old..new excludes everything reachable from old, old^..new everything
+	// reachable from the first parent of old.
+	boundary := oldCommit
+	if !opts.ExcludeOld {
+		boundary = nil
+		if oldCommit.NumParents() > 0 {
+			if boundary, err = oldCommit.Parent(0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	excluded := make(map[plumbing.Hash]bool)
+	if boundary != nil {
+		err = object.NewCommitPreorderIter(boundary, nil, nil).ForEach(func(c *object.Commit) error {
+			excluded[c.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var selected []*object.Commit
+	if opts.FirstParent {
+		for c := newCommit; c != nil && !excluded[c.Hash]; {
+			selected = append(selected, c)
+			if c.NumParents() == 0 {
+				break
+			}
+			if c, err = c.Parent(0); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		err = object.NewCommitPreorderIter(newCommit, excluded, nil).ForEach(func(c *object.Commit) error {
+			selected = append(selected, c)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Same default order as git log: newest committer date first.
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].Committer.When.After(selected[j].Committer.When)
+	})
+
+	refs := b.refNames()
+	commits := make([]CommitInfo, 0, len(selected))
+	for _, c := range selected {
+		info, err := b.commitInfo(c, refs, opts.MergeDiffs)
+		if err != nil {
+			return nil, err
+		}
+		commits = append(commits, info)
+	}
+
+	return commits, nil
+}
+
+func (b *GoGitBackend) MergeBase(a string, c string) (string, error) {
+	first, err := b.commit(a)
+	if err != nil {
+		return "", err
+	}
+	second, err := b.commit(c)
+	if err != nil {
+		return "", err
+	}
+
+	bases, err := first.MergeBase(second)
+	if err != nil {
+		return "", err
+	}
+	if len(bases) == 0 {
+		return "", fmt.Errorf("no merge base between %q and %q", a, c)
+	}
+	return bases[0].Hash.String(), nil
+}
+
+func (b *GoGitBackend) ResolveRevision(revision string) (string, error) {
+	c, err := b.commit(revision)
+	if err != nil {
+		return "", err
+	}
+	return c.Hash.String(), nil
+}
+
+// commit resolves a revision (hash, branch, tag, HEAD~1, ...) to a commit.
+func (b *GoGitBackend) commit(revision string) (*object.Commit, error) {
+	hash, err := b.repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return nil, fmt.Errorf("resolving revision %q: %w", revision, err)
+	}
+	return b.repo.CommitObject(*hash)
+}
+
+// commitInfo converts a go-git commit into a CommitInfo, diffing it against
+// its first parent like git log does. Merge commits are only diffed when
+// mergeDiffs is set.
+func (b *GoGitBackend) commitInfo(c *object.Commit, refs map[plumbing.Hash][]string, mergeDiffs bool) (CommitInfo, error) {
+	title, body := splitMessage(c.Message)
+
+	var parents []string
+	for _, parent := range c.ParentHashes {
+		parents = append(parents, parent.String())
+	}
+
+	changes := []FileChangeInfo{}
+	// git log shows no diff for merge commits unless asked to.
+	if c.NumParents() <= 1 || mergeDiffs {
+		var err error
+		changes, err = commitChanges(c)
+		if err != nil {
+			return CommitInfo{}, fmt.Errorf("commit %s: %w", c.Hash, err)
+		}
+	}
+
+	var additions, deletions int
+	for _, change := range changes {
+		additions += change.Additions
+		deletions += change.Deletions
+	}
+
+	var refNames string
+	if names := refs[c.Hash]; len(names) > 0 {
+		refNames = "(" + strings.Join(names, ", ") + ")"
+	}
+
+	return CommitInfo{
+		Hash:           c.Hash.String(),
+		Name:           c.Author.Name,
+		Email:          c.Author.Email,
+		Username:       usernameFromEmail(c.Author.Email, c.Author.Name),
+		AuthorTime:     strconv.FormatInt(c.Author.When.Unix(), 10),
+		CommitterName:  c.Committer.Name,
+		CommitterEmail: c.Committer.Email,
+		RefNames:       refNames,
+		Title:          title,
+		Body:           body,
+		Parents:        parents,
+		IsMerge:        len(parents) > 1,
+		Additions:      additions,
+		Deletions:      deletions,
+		Changes:        changes,
+	}, nil
+}
+
+// diffTreeOptions detect renames from 50% similarity, the threshold of git's
+// --find-renames used by the exec backend.
+var diffTreeOptions = &object.DiffTreeOptions{
+	DetectRenames: true,
+	RenameScore:   50,
+}
+
+// commitChanges diffs a commit against its first parent, or against the
+// empty tree for root commits, with rename detection enabled.
+func commitChanges(c *object.Commit) ([]FileChangeInfo, error) {
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	var parentTree *object.Tree
+	if c.NumParents() > 0 {
+		parent, err := c.Parent(0)
+		if err != nil {
+			return nil, err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return nil, err
+		}
+	}
+
+	diff, err := object.DiffTreeWithOptions(context.Background(), parentTree, tree, diffTreeOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make([]FileChangeInfo, 0, len(diff))
+	for _, d := range diff {
+		var status string
+		var paths []string
+		switch {
+		case d.From.Name == "":
+			status, paths = "A", []string{d.To.Name}
+		case d.To.Name == "":
+			status, paths = "D", []string{d.From.Name}
+		case d.From.Name != d.To.Name:
+			from, to, err := d.Files()
+			if err != nil {
+				return nil, err
+			}
+			score, err := renameSimilarity(from, to)
+			if err != nil {
+				return nil, err
+			}
+			status, paths = fmt.Sprintf("R%03d", score), []string{d.From.Name, d.To.Name}
+		default:
+			status, paths = "M", []string{d.From.Name}
+		}
+
+		change, err := newFileChange(status, paths)
+		if err != nil {
+			return nil, err
+		}
+
+		patch, err := d.Patch()
+		if err != nil {
+			return nil, err
+		}
+		for _, filePatch := range patch.FilePatches() {
+			if filePatch.IsBinary() {
+				change.Binary = true
+			}
+		}
+		if !change.Binary {
+			for _, stat := range patch.Stats() {
+				change.Additions += stat.Addition
+				change.Deletions += stat.Deletion
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].FileName < changes[j].FileName
+	})
+
+	return changes, nil
+}
+
+// renameSimilarity scores how similar the two sides of a rename are, the way
+// git does for the R<score> status of the exec backend: the bytes of the
+// source found again in the destination, over the size of the larger file.
+// Content is compared in chunks ending at a newline or 64 bytes long.
+func renameSimilarity(from *object.File, to *object.File) (int, error) {
+	if from.Hash == to.Hash {
+		return 100, nil
+	}
+
+	src, err := fileChunks(from)
+	if err != nil {
+		return 0, err
+	}
+	dst, err := fileChunks(to)
+	if err != nil {
+		return 0, err
+	}
+
+	var copied int64
+	for chunk, srcBytes := range src {
+		if dstBytes, ok := dst[chunk]; ok {
+			if dstBytes < srcBytes {
+				srcBytes = dstBytes
+			}
+			copied += srcBytes
+		}
+	}
+
+	maxSize := from.Size
+	if to.Size > maxSize {
+		maxSize = to.Size
+	}
+	if maxSize == 0 {
+		return 100, nil
+	}
+	// git computes the score out of 60000 before turning it into a
+	// percentage, both rounded down.
+	const maxScore = 60000
+	score := copied * maxScore / maxSize
+	return int(score * 100 / maxScore), nil
+}
+
+// fileChunks splits a file into chunks ending at a newline or 64 bytes long,
+// and sums the bytes of each distinct chunk.
+func fileChunks(file *object.File) (map[string]int64, error) {
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make(map[string]int64)
+	start := 0
+	for i := 0; i < len(contents); i++ {
+		if contents[i] == '\n' || i+1-start == 64 {
+			chunks[contents[start:i+1]] += int64(i + 1 - start)
+			start = i + 1
+		}
+	}
+	if start < len(contents) {
+		chunks[contents[start:]] += int64(len(contents) - start)
+	}
+	return chunks, nil
+}
+
+// refNames maps commit hashes to the branches and tags pointing at them,
+// formatted like git's %d decoration.
+func (b *GoGitBackend) refNames() map[plumbing.Hash][]string {
+	names := make(map[plumbing.Hash][]string)
+
+	var headTarget plumbing.ReferenceName
+	if head, err := b.repo.Reference(plumbing.HEAD, false); err == nil && head.Type() == plumbing.SymbolicReference {
+		headTarget = head.Target()
+	}
+	if head, err := b.repo.Head(); err == nil {
+		name := "HEAD"
+		if headTarget != "" {
+			name = "HEAD -> " + headTarget.Short()
+		}
+		names[head.Hash()] = append(names[head.Hash()], name)
+	}
+
+	refs, err := b.repo.References()
+	if err != nil {
+		return names
+	}
+	_ = refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference || ref.Name() == headTarget {
+			return nil
+		}
+		hash := ref.Hash()
+		switch {
+		case ref.Name().IsTag():
+			if tag, err := b.repo.TagObject(hash); err == nil {
+				hash = tag.Target
+			}
+			names[hash] = append(names[hash], "tag: "+ref.Name().Short())
+		case ref.Name().IsBranch(), ref.Name().IsRemote():
+			names[hash] = append(names[hash], ref.Name().Short())
+		}
+		return nil
+	})
+
+	return names
+}
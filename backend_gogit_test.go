@@ -0,0 +1,214 @@
+package main
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// memoryRepo is an in-memory repository built commit by commit.
+type memoryRepo struct {
+	t        *testing.T
+	repo     *gogit.Repository
+	worktree *gogit.Worktree
+	clock    time.Time
+}
+
+func newMemoryRepo(t *testing.T) *memoryRepo {
+	t.Helper()
+	repo, err := gogit.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &memoryRepo{t: t, repo: repo, worktree: worktree, clock: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)}
+}
+
+func (r *memoryRepo) write(path string, content string) {
+	r.t.Helper()
+	if err := util.WriteFile(r.worktree.Filesystem, path, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+	if _, err := r.worktree.Add(path); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *memoryRepo) move(from string, to string) {
+	r.t.Helper()
+	if _, err := r.worktree.Move(from, to); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+func (r *memoryRepo) checkout(branch string, create bool) {
+	r.t.Helper()
+	err := r.worktree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.NewBranchReferenceName(branch), Create: create, Keep: true})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// commit commits the staged changes an hour after the previous commit. The
+// parents default to HEAD.
+func (r *memoryRepo) commit(message string, parents ...plumbing.Hash) string {
+	r.t.Helper()
+	r.clock = r.clock.Add(time.Hour)
+	signature := &object.Signature{Name: "Ada Lovelace", Email: "ada@example.com", When: r.clock}
+	hash, err := r.worktree.Commit(message, &gogit.CommitOptions{Author: signature, Committer: signature, Parents: parents})
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return hash.String()
+}
+
+// lines returns the numbers from first to last, one per line, like seq.
+func lines(first int, last int) string {
+	var b strings.Builder
+	for i := first; i <= last; i++ {
+		b.WriteString(strconv.Itoa(i) + "\n")
+	}
+	return b.String()
+}
+
+func TestGoGitBackend(t *testing.T) {
+	r := newMemoryRepo(t)
+
+	r.write("notes.txt", lines(1, 20))
+	r.write("main.go", "package main\n\nfunc main() {}\n")
+	root := r.commit("Initial commit")
+
+	r.move("notes.txt", "docs.txt")
+	r.write("docs.txt", lines(1, 21))
+	rename := r.commit("Rename notes\n\nBody; with semicolon")
+
+	r.write("logo.png", "\x00\x01\x02binary")
+	binary := r.commit("Add logo")
+
+	r.checkout("feature", true)
+	r.write("main.go", "package main\n\nfunc main() {}\n// feature\n")
+	feature := r.commit("Add feature comment")
+
+	r.checkout("master", false)
+	r.write("main.go", "package main\n\nfunc main() {}\n")
+	r.write("docs.txt", lines(1, 22))
+	docs := r.commit("Extend docs")
+
+	r.write("main.go", "package main\n\nfunc main() {}\n// feature\n")
+	merge := r.commit("Merge branch 'feature'", plumbing.NewHash(docs), plumbing.NewHash(feature))
+
+	backend := NewGoGitBackend(r.repo)
+
+	t.Run("Log", func(t *testing.T) {
+		commits, err := backend.Log(LogOptions{Old: root, New: "master"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := hashes(commits), []string{merge, docs, feature, binary, rename, root}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("got commits %v, want %v", got, want)
+		}
+
+		mergeInfo := commits[0]
+		if !mergeInfo.IsMerge || !reflect.DeepEqual(mergeInfo.Parents, []string{docs, feature}) {
+			t.Errorf("merge commit: got IsMerge %v, parents %v", mergeInfo.IsMerge, mergeInfo.Parents)
+		}
+		if len(mergeInfo.Changes) != 0 {
+			t.Errorf("merge commit: got changes %+v without MergeDiffs, want none", mergeInfo.Changes)
+		}
+
+		renameInfo := commits[4]
+		if renameInfo.Title != "Rename notes" || renameInfo.Body != "Body; with semicolon" {
+			t.Errorf("rename commit: got title %q, body %q", renameInfo.Title, renameInfo.Body)
+		}
+		// The same rename is reported as R094 by git, see testdata/gitlog/linear.log.
+		wantRename := []FileChangeInfo{{FileName: "docs.txt", Status: "R", OldPath: "notes.txt", NewPath: "docs.txt", Similarity: 94, Additions: 1}}
+		if !reflect.DeepEqual(renameInfo.Changes, wantRename) {
+			t.Errorf("rename commit: got changes %+v, want %+v", renameInfo.Changes, wantRename)
+		}
+
+		wantBinary := []FileChangeInfo{{FileName: "logo.png", Status: "A", NewPath: "logo.png", Binary: true}}
+		if !reflect.DeepEqual(commits[3].Changes, wantBinary) {
+			t.Errorf("binary commit: got changes %+v, want %+v", commits[3].Changes, wantBinary)
+		}
+	})
+
+	t.Run("MergeDiffs", func(t *testing.T) {
+		commits, err := backend.Log(LogOptions{Old: merge, New: merge, MergeDiffs: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []FileChangeInfo{{FileName: "main.go", Status: "M", OldPath: "main.go", NewPath: "main.go", Additions: 1}}
+		if len(commits) != 1 || !reflect.DeepEqual(commits[0].Changes, want) {
+			t.Errorf("got %+v, want the merge commit with changes %+v", commits, want)
+		}
+	})
+
+	t.Run("FirstParent", func(t *testing.T) {
+		commits, err := backend.Log(LogOptions{Old: root, New: "master", FirstParent: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := hashes(commits), []string{merge, docs, binary, rename, root}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got commits %v, want %v", got, want)
+		}
+	})
+
+	t.Run("ExcludeOld", func(t *testing.T) {
+		commits, err := backend.Log(LogOptions{Old: binary, New: "master", ExcludeOld: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := hashes(commits), []string{merge, docs, feature}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got commits %v, want %v", got, want)
+		}
+	})
+
+	t.Run("MergeBase", func(t *testing.T) {
+		base, err := backend.MergeBase("master~1", "feature")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if base != binary {
+			t.Errorf("got merge base %s, want %s", base, binary)
+		}
+	})
+
+	t.Run("ResolveRevision", func(t *testing.T) {
+		for revision, want := range map[string]string{
+			"feature":  feature,
+			"master~1": docs,
+			"HEAD^2":   feature,
+			binary:     binary,
+		} {
+			got, err := backend.ResolveRevision(revision)
+			if err != nil {
+				t.Errorf("ResolveRevision(%q): %v", revision, err)
+			} else if got != want {
+				t.Errorf("ResolveRevision(%q) = %s, want %s", revision, got, want)
+			}
+		}
+		if _, err := backend.ResolveRevision("missing"); err == nil {
+			t.Error("ResolveRevision(\"missing\") succeeded, want an error")
+		}
+	})
+}
+
+func hashes(commits []CommitInfo) []string {
+	var result []string
+	for _, commit := range commits {
+		result = append(result, commit.Hash)
+	}
+	return result
+}
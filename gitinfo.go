@@ -1,10 +1,9 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
-	"os/exec"
-	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -13,154 +12,355 @@ type CommitInfo struct {
 	Name           string
 	Email          string
 	Username       string
+	Author         Person
 	AuthorTime     string
 	CommitterName  string
 	CommitterEmail string
 	RefNames       string
 	Title          string
 	Body           string
-	ParentHashes   string
+	Parents        []string
+	IsMerge        bool
+	CoAuthors      []Person
+	ReviewedBy     []Person
+	SignedOffBy    []Person
+	Additions      int
+	Deletions      int
 	Changes        []FileChangeInfo
 }
 
 type FileChangeInfo struct {
-	FileName string
-	Status   string
+	FileName   string
+	Status     string
+	OldPath    string
+	NewPath    string
+	Similarity int
+	Additions  int
+	Deletions  int
+	Binary     bool
 }
 
+// FileInfo lists the commits that touched a file, newest first.
 type FileInfo struct {
 	FileName      string
 	CommitDetails []CommitInfo
 }
 
-func GetCommitInfo(olderCommitHash string, newerCommitHash string) ([]FileInfo, error) {
+// CommitHistory is the result of walking a commit range. Commits holds every
+// commit exactly once in git log order, Files is an index derived from it.
+type CommitHistory struct {
+	Commits []CommitInfo
+	Files   []FileInfo
+}
+
+// NewCommitHistory builds a CommitHistory from a list of commits, indexing
+// each commit under every file it changed. Files are sorted by name.
+func NewCommitHistory(commits []CommitInfo) *CommitHistory {
+	fileIndex := make(map[string]int)
+	var files []FileInfo
 
-	// Check if git is installed and configure it
-	if _, err := exec.LookPath("git"); err == nil {
-		cmd := exec.Command("sh", "-c", "git config --global --add safe.directory '*' || true")
-		if err := cmd.Run(); err != nil {
-			fmt.Println("| \033[1;31mError configuring git:\033[0m", err)
-			return nil, err
+	for _, commit := range commits {
+		for _, change := range commit.Changes {
+			i, ok := fileIndex[change.FileName]
+			if !ok {
+				i = len(files)
+				fileIndex[change.FileName] = i
+				files = append(files, FileInfo{FileName: change.FileName})
+			}
+			// A commit touches a path at most once, but guard against
+			// indexing it twice if git reports the same path again.
+			details := files[i].CommitDetails
+			if len(details) > 0 && details[len(details)-1].Hash == commit.Hash {
+				continue
+			}
+			files[i].CommitDetails = append(details, commit)
 		}
-		fmt.Println("| \033[1;36mGit is installed and configured.\033[0m")
-	} else {
-		fmt.Println("| \033[1;31mGit is not installed on the system.\033[0m")
-		return nil, err
 	}
 
-	fmt.Println("| \033[1;36mGetting commit info...\033[0m")
-	var commitSearch string
-	olderCommitHash = strings.TrimSpace(olderCommitHash)
-	newerCommitHash = strings.TrimSpace(newerCommitHash)
-	if olderCommitHash == newerCommitHash {
-		commitSearch = olderCommitHash
-	} else {
-		commitSearch = olderCommitHash + "^.." + newerCommitHash
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].FileName < files[j].FileName
+	})
+
+	return &CommitHistory{
+		Commits: commits,
+		Files:   files,
+	}
+}
+
+// MergeHistories combines the histories of several ranges, e.g. the refs of a
+// push, keeping commits found in more than one range once.
+func MergeHistories(histories ...*CommitHistory) *CommitHistory {
+	seen := make(map[string]struct{})
+	var commits []CommitInfo
+	for _, history := range histories {
+		for _, commit := range history.Commits {
+			if _, ok := seen[commit.Hash]; ok {
+				continue
+			}
+			seen[commit.Hash] = struct{}{}
+			commits = append(commits, commit)
+		}
+	}
+	return NewCommitHistory(commits)
+}
+
+// MergeGroup is a merge commit together with the commits it brought into the
+// branch, i.e. the commits reachable from its other parents but not from its
+// first one.
+type MergeGroup struct {
+	Merge   CommitInfo
+	Commits []CommitInfo
+}
+
+// GroupMerges groups the commits of the history under the merge commit that
+// brought them in. Only commits inside the history are considered, and a
+// commit merged several times (nested merges) belongs to the oldest merge.
+func GroupMerges(commits []CommitInfo) []MergeGroup {
+	byHash := make(map[string]CommitInfo, len(commits))
+	for _, commit := range commits {
+		byHash[commit.Hash] = commit
+	}
+
+	// ancestors walks the history from the given hashes, staying inside it.
+	ancestors := func(from []string) map[string]struct{} {
+		seen := make(map[string]struct{})
+		stack := append([]string(nil), from...)
+		for len(stack) > 0 {
+			hash := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			commit, ok := byHash[hash]
+			if _, visited := seen[hash]; visited || !ok {
+				continue
+			}
+			seen[hash] = struct{}{}
+			stack = append(stack, commit.Parents...)
+		}
+		return seen
+	}
+
+	assigned := make(map[string]struct{})
+	var groups []MergeGroup
+	// git log lists the newest commits first, walk oldest first so nested
+	// merges claim their commits before the merges that contain them.
+	for i := len(commits) - 1; i >= 0; i-- {
+		merge := commits[i]
+		if !merge.IsMerge {
+			continue
+		}
+
+		mainline := ancestors(merge.Parents[:1])
+		merged := ancestors(merge.Parents[1:])
+
+		group := MergeGroup{Merge: merge}
+		for _, commit := range commits {
+			if _, ok := merged[commit.Hash]; !ok {
+				continue
+			}
+			if _, ok := mainline[commit.Hash]; ok {
+				continue
+			}
+			if _, ok := assigned[commit.Hash]; ok {
+				continue
+			}
+			assigned[commit.Hash] = struct{}{}
+			group.Commits = append(group.Commits, commit)
+		}
+		groups = append(groups, group)
 	}
 
-	// Run git status using sh
-	statusCmd := exec.Command("sh", "-c", "git status")
-	statusOut, statusErr := statusCmd.Output()
-	if statusErr != nil {
-		fmt.Println("| \033[1;31mError checking git status:\033[0m", statusErr)
-		return nil, statusErr
+	// Newest merge first, like the rest of the report.
+	for i, j := 0, len(groups)-1; i < j; i, j = i+1, j-1 {
+		groups[i], groups[j] = groups[j], groups[i]
 	}
-	fmt.Println("| \033[1;36mGit Status:\033[0m\n", string(statusOut))
 
-	cmd := exec.Command("git", "log", "--pretty=format:%H;%an;%ae;%aN;%at;%cN;%cE;%d;%s;%b;%p", "--name-status", commitSearch)
-	fmt.Println("| \033[1;36mCommand:\033[0m " + cmd.String())
+	return groups
+}
+
+// Separators used in the git log output. Record and unit separators can not
+// appear in commit metadata, so titles and bodies may contain anything else
+// (semicolons, newlines, ...) without breaking the parsing.
+const (
+	recordSeparator = "\x1e"
+	fieldSeparator  = "\x1f"
+)
+
+// gitLogFormat is the --pretty format passed to git log. Every commit starts
+// with a record separator and every field is terminated by a unit separator,
+// the -z --raw and --numstat entries follow the last one.
+const gitLogFormat = "%x1e%H%x1f%an%x1f%ae%x1f%aN%x1f%at%x1f%cN%x1f%cE%x1f%d%x1f%s%x1f%b%x1f%P%x1f"
 
-	var out, stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
-	err := cmd.Run()
+// gitLogFields is the number of fields in gitLogFormat.
+const gitLogFields = 11
+
+// GetCommitInfo walks the commits selected by opts (see ResolveRange) using
+// the given backend, and resolves the people involved to canonical
+// identities.
+func GetCommitInfo(backend GitBackend, opts LogOptions, identities *IdentityResolver) (*CommitHistory, error) {
+	fmt.Println("| \033[1;36mGetting commit info...\033[0m")
+	fmt.Printf("| \033[1;36mGit Backend:\033[0m \033[1;32m%s\033[0m\n", backend.Name())
+
+	commits, err := backend.Log(opts)
 	if err != nil {
-		fmt.Println("| \033[1;31mError executing command:\033[0m", err)
-		fmt.Println("| \033[1;31mGit error details:\033[0m", stderr.String())
+		fmt.Println("| \033[1;31mError reading commits:\033[0m", err)
 		return nil, err
 	}
 
-	// fmt.Println("|---------------------------------------------")
-	// fmt.Println(out.String())
-	// fmt.Println("|---------------------------------------------")
+	for i := range commits {
+		applyTrailers(&commits[i])
+		identities.ResolveCommit(&commits[i])
+	}
 
-	fileCommitMap := make(map[string][]CommitInfo)
-	lines := strings.Split(out.String(), "\n")
-	var currentCommitInfo CommitInfo
+	return NewCommitHistory(commits), nil
+}
 
-	for _, line := range lines {
-		if line == "" {
+// parseGitLog parses the output of git log -z --raw --numstat using gitLogFormat.
+func parseGitLog(output string) ([]CommitInfo, error) {
+	var commits []CommitInfo
+
+	for _, record := range strings.Split(output, recordSeparator) {
+		if strings.Trim(record, "\x00\n") == "" {
 			continue
 		}
-		if strings.Contains(line, ";") {
-			parts := strings.Split(line, ";")
-			if len(parts) >= 11 {
-				username := parts[3]
-				emailRegex := regexp.MustCompile(`\+(\w+)@users\.noreply\.github\.com`)
-				emailMatch := emailRegex.FindStringSubmatch(parts[2])
-				if emailMatch != nil {
-					username = emailMatch[1]
-				}
-				currentCommitInfo = CommitInfo{
-					Hash:           parts[0],
-					Name:           parts[1],
-					Email:          parts[2],
-					Username:       username,
-					AuthorTime:     parts[4],
-					CommitterName:  parts[5],
-					CommitterEmail: parts[6],
-					RefNames:       parts[7],
-					Title:          parts[8],
-					Body:           parts[9],
-					ParentHashes:   parts[10],
-					Changes:        []FileChangeInfo{},
-				}
+
+		parts := strings.SplitN(record, fieldSeparator, gitLogFields+1)
+		if len(parts) != gitLogFields+1 {
+			return nil, fmt.Errorf("malformed git log record: expected %d fields, got %d", gitLogFields, len(parts)-1)
+		}
+
+		changes, err := parseDiffSection(parts[gitLogFields])
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: %w", parts[0], err)
+		}
+
+		var additions, deletions int
+		for _, change := range changes {
+			additions += change.Additions
+			deletions += change.Deletions
+		}
+
+		parents := strings.Fields(parts[10])
+
+		commits = append(commits, CommitInfo{
+			Hash:           parts[0],
+			Name:           parts[1],
+			Email:          parts[2],
+			Username:       usernameFromEmail(parts[2], parts[3]),
+			AuthorTime:     parts[4],
+			CommitterName:  parts[5],
+			CommitterEmail: parts[6],
+			RefNames:       strings.TrimSpace(parts[7]),
+			Title:          parts[8],
+			Body:           strings.TrimRight(parts[9], "\n"),
+			Parents:        parents,
+			IsMerge:        len(parents) > 1,
+			Additions:      additions,
+			Deletions:      deletions,
+			Changes:        changes,
+		})
+	}
+
+	return commits, nil
+}
+
+// newFileChange builds a FileChangeInfo from a raw status and its paths.
+// Renames and copies come with a similarity score (e.g. R087) and both the
+// source and destination paths, FileName is always the resulting path.
+func newFileChange(status string, paths []string) (FileChangeInfo, error) {
+	change := FileChangeInfo{Status: status[:1]}
+
+	if len(status) > 1 {
+		similarity, err := strconv.Atoi(status[1:])
+		if err != nil {
+			return FileChangeInfo{}, fmt.Errorf("malformed status %q: %w", status, err)
+		}
+		change.Similarity = similarity
+	}
+
+	switch change.Status {
+	case "R", "C":
+		change.OldPath = paths[0]
+		change.NewPath = paths[1]
+	case "A":
+		change.NewPath = paths[0]
+	case "D":
+		change.OldPath = paths[0]
+	default:
+		change.OldPath = paths[0]
+		change.NewPath = paths[0]
+	}
+
+	change.FileName = paths[len(paths)-1]
+
+	return change, nil
+}
+
+// parseDiffSection parses the NUL separated --raw and --numstat entries of a
+// commit. Raw entries come first and give the status of each file, numstat
+// entries follow in the same order with the line counts.
+func parseDiffSection(section string) ([]FileChangeInfo, error) {
+	changes := []FileChangeInfo{}
+
+	section = strings.TrimLeft(section, "\n")
+	section = strings.TrimRight(section, "\x00")
+	if section == "" {
+		return changes, nil
+	}
+
+	tokens := strings.Split(section, "\x00")
+	numstat := 0
+	for i := 0; i < len(tokens); {
+		token := tokens[i]
+
+		if strings.HasPrefix(token, ":") {
+			// :<old mode> <new mode> <old sha> <new sha> <status>\0<path>[\0<new path>]
+			fields := strings.Fields(token)
+			status := fields[len(fields)-1]
+			paths := 1
+			if strings.HasPrefix(status, "R") || strings.HasPrefix(status, "C") {
+				paths = 2
 			}
-		} else {
-			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				changeInfo := FileChangeInfo{
-					FileName: parts[1],
-					Status:   parts[0],
-				}
-				currentCommitInfo.Changes = append(currentCommitInfo.Changes, changeInfo)
+			if i+paths >= len(tokens) {
+				return nil, fmt.Errorf("missing path for status %q", status)
 			}
+			change, err := newFileChange(status, tokens[i+1:i+1+paths])
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, change)
+			i += paths + 1
+			continue
 		}
-		if len(currentCommitInfo.Changes) > 0 {
-			fileCommitMap[currentCommitInfo.Changes[0].FileName] = append(fileCommitMap[currentCommitInfo.Changes[0].FileName], currentCommitInfo)
-			currentCommitInfo.Changes = currentCommitInfo.Changes[1:]
+
+		// <added>\t<deleted>\t<path>, or with an empty path followed by the
+		// old and new paths for renames and copies.
+		fields := strings.SplitN(token, "\t", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("malformed numstat entry %q", token)
+		}
+		i++
+		if fields[2] == "" {
+			i += 2
+		}
+		if numstat >= len(changes) {
+			return nil, fmt.Errorf("numstat entry %q without matching raw entry", token)
 		}
-	}
 
-	var result []FileInfo
-	for file, commits := range fileCommitMap {
-		// fmt.Println("|---------------------------------------------")
-		// fmt.Printf("| Commits: %v\n", len(commits))
-		result = append(result, FileInfo{
-			FileName:      file,
-			CommitDetails: commits,
-		})
-		// fmt.Printf("| File: %s\n", file)
-		// fmt.Println("|---------------------------------------------")
-		// for _, commit := range commits {
-		// 	fmt.Printf("| Commit: %s\n", commit.Hash)
-		// 	fmt.Printf("| Author Name: %s\n", commit.Name)
-		// 	fmt.Printf("| Author Email: %s\n", commit.Email)
-		// 	fmt.Printf("| Username: %s\n", commit.Username)
-		// 	fmt.Printf("| Author Time: %s\n", commit.AuthorTime)
-		// 	fmt.Printf("| Committer Name: %s\n", commit.CommitterName)
-		// 	fmt.Printf("| Committer Email: %s\n", commit.CommitterEmail)
-		// 	fmt.Printf("| Ref Names: %s\n", commit.RefNames)
-		// 	fmt.Printf("| Message Title: %s\n", commit.Title)
-		// 	fmt.Printf("| Message Body: %s\n", commit.Body)
-		// 	fmt.Printf("| Parent Hashes: %s\n", commit.ParentHashes)
-		// 	fmt.Println("|---------------------------------------------")
-		// 	for _, change := range commit.Changes {
-		// 		fmt.Printf("| File: %s | Status: %s\n", change.FileName, change.Status)
-		// 	}
-		// 	fmt.Println("|---------------------------------------------")
-		// }
+		if fields[0] == "-" && fields[1] == "-" {
+			changes[numstat].Binary = true
+		} else {
+			additions, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("malformed numstat entry %q: %w", token, err)
+			}
+			deletions, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("malformed numstat entry %q: %w", token, err)
+			}
+			changes[numstat].Additions = additions
+			changes[numstat].Deletions = deletions
+		}
+		numstat++
 	}
 
-	return result, nil
+	return changes, nil
 }
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The fixtures in testdata/gitlog are recorded outputs of
+//
+//	git log -z --pretty=format:<gitLogFormat> --raw --numstat --find-renames --find-copies
+//
+// run against a small repository, merge-diffs.log with --diff-merges=first-parent.
+
+func readGitLog(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "gitlog", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseGitLog(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    []CommitInfo
+	}{
+		{
+			fixture: "linear.log",
+			want: []CommitInfo{
+				{
+					Hash:           "0bfe0712a4fa5e1615905c32e59a25265a24dae7",
+					Name:           "Grace Hopper",
+					Email:          "12345+grace@users.noreply.github.com",
+					Username:       "grace",
+					AuthorTime:     "1704196800",
+					CommitterName:  "Ada Lovelace",
+					CommitterEmail: "ada@example.com",
+					// %s joins the lines of the subject.
+					Title:     "Copy; binary  asset",
+					Parents:   []string{"3a97b8189df6dd06b7c11e917efe212a3932fa3b"},
+					Additions: 1,
+					Changes: []FileChangeInfo{
+						{FileName: "copied.txt", Status: "C", OldPath: "copy-src.txt", NewPath: "copied.txt", Similarity: 100},
+						{FileName: "copy-src.txt", Status: "M", OldPath: "copy-src.txt", NewPath: "copy-src.txt", Additions: 1},
+						{FileName: "logo.png", Status: "A", NewPath: "logo.png", Binary: true},
+					},
+				},
+				{
+					Hash:           "3a97b8189df6dd06b7c11e917efe212a3932fa3b",
+					Name:           "Ada Lovelace",
+					Email:          "ada@example.com",
+					Username:       "Ada Lovelace",
+					AuthorTime:     "1704193200",
+					CommitterName:  "Ada Lovelace",
+					CommitterEmail: "ada@example.com",
+					Title:          "Rename notes; add copy source",
+					Body:           "Body line one; with semicolon\nBody line two\n\nCo-authored-by: Grace Hopper <grace@example.com>",
+					Parents:        []string{"3ea1eb887e54a47d88d3afec3bd007574807c660"},
+					Additions:      1,
+					Changes: []FileChangeInfo{
+						{FileName: "copy-src.txt", Status: "C", OldPath: "notes.txt", NewPath: "copy-src.txt", Similarity: 100},
+						{FileName: "docs.txt", Status: "R", OldPath: "notes.txt", NewPath: "docs.txt", Similarity: 94, Additions: 1},
+					},
+				},
+				{
+					Hash:           "3ea1eb887e54a47d88d3afec3bd007574807c660",
+					Name:           "Ada Lovelace",
+					Email:          "ada@example.com",
+					Username:       "Ada Lovelace",
+					AuthorTime:     "1704189600",
+					CommitterName:  "Ada Lovelace",
+					CommitterEmail: "ada@example.com",
+					Title:          "Initial commit",
+					Parents:        []string{},
+					Additions:      23,
+					Changes: []FileChangeInfo{
+						{FileName: "main.go", Status: "A", NewPath: "main.go", Additions: 3},
+						{FileName: "notes.txt", Status: "A", NewPath: "notes.txt", Additions: 20},
+					},
+				},
+			},
+		},
+		{
+			// Without --diff-merges git reports no changes for merge commits.
+			fixture: "merge.log",
+			want: []CommitInfo{
+				mergeCommit(),
+				extendDocsCommit(),
+				featureCommit(),
+			},
+		},
+		{
+			fixture: "merge-diffs.log",
+			want: []CommitInfo{
+				func() CommitInfo {
+					merge := mergeCommit()
+					merge.Additions = 1
+					merge.Changes = []FileChangeInfo{
+						{FileName: "main.go", Status: "M", OldPath: "main.go", NewPath: "main.go", Additions: 1},
+					}
+					return merge
+				}(),
+				extendDocsCommit(),
+				featureCommit(),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			commits, err := parseGitLog(readGitLog(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("parseGitLog: %v", err)
+			}
+			if len(commits) != len(tt.want) {
+				t.Fatalf("got %d commits, want %d", len(commits), len(tt.want))
+			}
+			for i := range tt.want {
+				// Commits without changes have an empty, non-nil slice.
+				if tt.want[i].Changes == nil {
+					tt.want[i].Changes = []FileChangeInfo{}
+				}
+				if !reflect.DeepEqual(commits[i], tt.want[i]) {
+					t.Errorf("commit %d:\n got %+v\nwant %+v", i, commits[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func mergeCommit() CommitInfo {
+	return CommitInfo{
+		Hash:           "5d1ff36a21f2fec4216a4a0a5633202cec7c4ab0",
+		Name:           "Ada Lovelace",
+		Email:          "ada@example.com",
+		Username:       "Ada Lovelace",
+		AuthorTime:     "1704279600",
+		CommitterName:  "Ada Lovelace",
+		CommitterEmail: "ada@example.com",
+		RefNames:       "(HEAD -> main)",
+		Title:          "Merge branch 'feature'",
+		Parents:        []string{"434e3d79845911ea71f029493e1a0a886d49213b", "c544621cdad3a3942852356ebbeeda2df3e41c32"},
+		IsMerge:        true,
+	}
+}
+
+func extendDocsCommit() CommitInfo {
+	return CommitInfo{
+		Hash:           "434e3d79845911ea71f029493e1a0a886d49213b",
+		Name:           "Ada Lovelace",
+		Email:          "ada@example.com",
+		Username:       "Ada Lovelace",
+		AuthorTime:     "1704276000",
+		CommitterName:  "Ada Lovelace",
+		CommitterEmail: "ada@example.com",
+		Title:          "Extend docs",
+		Parents:        []string{"0bfe0712a4fa5e1615905c32e59a25265a24dae7"},
+		Additions:      1,
+		Changes: []FileChangeInfo{
+			{FileName: "docs.txt", Status: "M", OldPath: "docs.txt", NewPath: "docs.txt", Additions: 1},
+		},
+	}
+}
+
+func featureCommit() CommitInfo {
+	return CommitInfo{
+		Hash:           "c544621cdad3a3942852356ebbeeda2df3e41c32",
+		Name:           "Ada Lovelace",
+		Email:          "ada@example.com",
+		Username:       "Ada Lovelace",
+		AuthorTime:     "1704272400",
+		CommitterName:  "Ada Lovelace",
+		CommitterEmail: "ada@example.com",
+		RefNames:       "(feature)",
+		Title:          "Add feature comment",
+		Parents:        []string{"0bfe0712a4fa5e1615905c32e59a25265a24dae7"},
+		Additions:      1,
+		Changes: []FileChangeInfo{
+			{FileName: "main.go", Status: "M", OldPath: "main.go", NewPath: "main.go", Additions: 1},
+		},
+	}
+}
+
+// gitLogRecord builds a git log record with the given diff section.
+func gitLogRecord(diff string) string {
+	fields := []string{"abc123", "Ada", "ada@example.com", "Ada", "1704189600", "Ada", "ada@example.com", "", "Title", "", ""}
+	return recordSeparator + strings.Join(fields, fieldSeparator) + fieldSeparator + diff
+}
+
+func TestParseGitLogErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "missing fields",
+			output: recordSeparator + "abc123" + fieldSeparator + "Ada" + fieldSeparator,
+			want:   "malformed git log record",
+		},
+		{
+			name:   "missing path",
+			output: gitLogRecord("\n:100644 100644 aaa bbb M"),
+			want:   "missing path",
+		},
+		{
+			name:   "missing rename destination",
+			output: gitLogRecord("\n:100644 100644 aaa bbb R090\x00old.txt"),
+			want:   "missing path",
+		},
+		{
+			name:   "malformed similarity",
+			output: gitLogRecord("\n:100644 100644 aaa bbb Rxx\x00old.txt\x00new.txt\x00"),
+			want:   "malformed status",
+		},
+		{
+			name:   "malformed numstat",
+			output: gitLogRecord("\n:100644 100644 aaa bbb M\x00a.txt\x00garbage\x00"),
+			want:   "malformed numstat entry",
+		},
+		{
+			name:   "non numeric numstat",
+			output: gitLogRecord("\n:100644 100644 aaa bbb M\x00a.txt\x00x\t1\ta.txt\x00"),
+			want:   "malformed numstat entry",
+		},
+		{
+			name:   "numstat without raw entry",
+			output: gitLogRecord("\n1\t0\ta.txt\x00"),
+			want:   "without matching raw entry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseGitLog(tt.output)
+			if err == nil {
+				t.Fatalf("parseGitLog succeeded, want an error containing %q", tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q does not contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGitLogEmpty(t *testing.T) {
+	commits, err := parseGitLog("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 0 {
+		t.Errorf("got %d commits, want none", len(commits))
+	}
+}
+
+func TestGroupMerges(t *testing.T) {
+	// commit builds a commit of the history, newest first like git log.
+	commit := func(hash string, parents ...string) CommitInfo {
+		return CommitInfo{Hash: hash, Title: hash, Parents: parents, IsMerge: len(parents) > 1}
+	}
+
+	tests := []struct {
+		name    string
+		commits []CommitInfo
+		// want maps each merge, newest first, to the commits it brought in.
+		want [][]string
+	}{
+		{
+			name: "nested merges",
+			commits: []CommitInfo{
+				commit("outer", "main", "inner"),
+				commit("inner", "feature", "sub"),
+				commit("sub", "base"),
+				commit("feature", "base"),
+				commit("main", "base"),
+				commit("base"),
+			},
+			want: [][]string{{"outer", "inner", "feature"}, {"inner", "sub"}},
+		},
+		{
+			name: "octopus merge",
+			commits: []CommitInfo{
+				commit("octopus", "main", "x", "y"),
+				commit("y", "base"),
+				commit("x", "base"),
+				commit("main", "base"),
+				commit("base"),
+			},
+			want: [][]string{{"octopus", "y", "x"}},
+		},
+		{
+			name: "merged parent outside the range",
+			commits: []CommitInfo{
+				commit("merge", "main", "outside"),
+				commit("main", "base"),
+				commit("base"),
+			},
+			want: [][]string{{"merge"}},
+		},
+		{
+			name: "every parent outside the range",
+			commits: []CommitInfo{
+				commit("merge", "outside-main", "topic"),
+				commit("topic", "outside-topic"),
+			},
+			want: [][]string{{"merge", "topic"}},
+		},
+		{
+			name: "shared ancestor merged twice",
+			commits: []CommitInfo{
+				commit("second", "first", "fix"),
+				commit("fix", "topic"),
+				commit("first", "main", "topic"),
+				commit("topic", "base"),
+				commit("main", "base"),
+				commit("base"),
+			},
+			want: [][]string{{"second", "fix"}, {"first", "topic"}},
+		},
+		{
+			name:    "no merges",
+			commits: []CommitInfo{commit("second", "first"), commit("first")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for _, group := range GroupMerges(tt.commits) {
+				hashes := []string{group.Merge.Hash}
+				for _, commit := range group.Commits {
+					hashes = append(hashes, commit.Hash)
+				}
+				got = append(got, hashes)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got groups %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"commit-insights/internal/models"
+)
+
+// DefaultHarnessURL is the Harness SaaS API endpoint.
+const DefaultHarnessURL = "https://app.harness.io"
+
+// HarnessClientConfig configures a HarnessClient.
+type HarnessClientConfig struct {
+	// BaseURL is the Harness installation, e.g. https://app.harness.io or a
+	// self-managed https://harness.example.com. It may contain a path prefix
+	// such as /gateway.
+	BaseURL string
+	// APIKey is sent in the x-api-key header.
+	APIKey string
+	// CABundle is a PEM file with extra certificate authorities to trust.
+	CABundle string
+	// Proxy is the proxy URL. When empty the HTTP(S)_PROXY environment
+	// variables are used.
+	Proxy string
+	// Timeout limits each request. Zero means no timeout.
+	Timeout time.Duration
+	// MaxRetries is the number of times a request failing with a transport
+	// error, a 429 or a 5xx status is retried.
+	MaxRetries int
+	// RetryWait is the delay before the first retry, doubled on every
+	// following one up to maxRetryWait. A Retry-After header takes
+	// precedence. Defaults to defaultRetryWait.
+	RetryWait time.Duration
+}
+
+const (
+	defaultRetryWait = time.Second
+	maxRetryWait     = 30 * time.Second
+)
+
+// Errors wrapped by HarnessAPIError, to be checked with errors.Is.
+var (
+	ErrHarnessUnauthorized = errors.New("harness: unauthorized")
+	ErrHarnessNotFound     = errors.New("harness: not found")
+	ErrHarnessRateLimited  = errors.New("harness: rate limited")
+	ErrHarnessServer       = errors.New("harness: server error")
+)
+
+// HarnessAPIError is returned when the API answers with a non 2xx status.
+type HarnessAPIError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Message is the message of the Harness error body, or the raw body
+	// when it is not a Harness error.
+	Message string
+}
+
+func (e *HarnessAPIError) Error() string {
+	msg := fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Unwrap classifies the error as one of the ErrHarness* errors.
+func (e *HarnessAPIError) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden:
+		return ErrHarnessUnauthorized
+	case e.StatusCode == http.StatusNotFound:
+		return ErrHarnessNotFound
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrHarnessRateLimited
+	case e.StatusCode >= 500:
+		return ErrHarnessServer
+	}
+	return nil
+}
+
+// retryable reports whether the request may succeed if sent again.
+func (e *HarnessAPIError) retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// HarnessScope identifies the account, organization and project of a call.
+type HarnessScope struct {
+	AccountID string
+	OrgID     string
+	ProjectID string
+}
+
+// values returns the scope as the query parameters expected by Harness.
+func (s HarnessScope) values() url.Values {
+	query := url.Values{}
+	query.Set("accountIdentifier", s.AccountID)
+	query.Set("orgIdentifier", s.OrgID)
+	query.Set("projectIdentifier", s.ProjectID)
+	return query
+}
+
+// HarnessClient calls the Harness platform API.
+type HarnessClient struct {
+	baseURL    *url.URL
+	apiKey     string
+	httpClient *http.Client
+	maxRetries int
+	retryWait  time.Duration
+	// sleep waits between retries, replaced in tests.
+	sleep func(time.Duration)
+}
+
+// NewHarnessClient creates a client from config.
+func NewHarnessClient(config HarnessClientConfig) (*HarnessClient, error) {
+	baseURL := strings.TrimSpace(config.BaseURL)
+	if baseURL == "" {
+		baseURL = DefaultHarnessURL
+	}
+	parsedURL, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return nil, fmt.Errorf("invalid Harness URL %q", config.BaseURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %w", config.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if config.CABundle != "" {
+		pem, err := os.ReadFile(config.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in the CA bundle " + config.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	retryWait := config.RetryWait
+	if retryWait <= 0 {
+		retryWait = defaultRetryWait
+	}
+
+	return &HarnessClient{
+		baseURL: parsedURL,
+		apiKey:  config.APIKey,
+		httpClient: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+		},
+		maxRetries: config.MaxRetries,
+		retryWait:  retryWait,
+		sleep:      time.Sleep,
+	}, nil
+}
+
+// BaseURL returns the Harness installation the client talks to.
+func (c *HarnessClient) BaseURL() string {
+	return c.baseURL.String()
+}
+
+// ExecutionSummaryFilter is the body of the execution summary request.
+type ExecutionSummaryFilter struct {
+	Status           []string `json:"status,omitempty"`
+	ModuleProperties struct {
+		CI struct {
+			BuildType string `json:"buildType,omitempty"`
+			Branch    string `json:"branch,omitempty"`
+			RepoName  string `json:"repoName,omitempty"`
+		} `json:"ci"`
+	} `json:"moduleProperties"`
+	FilterType string `json:"filterType"`
+}
+
+// ExecutionSummary lists the executions of a pipeline matching filter, newest
+// first.
+func (c *HarnessClient) ExecutionSummary(scope HarnessScope, pipelineID string, page int, size int, filter ExecutionSummaryFilter) (*models.Response, error) {
+	query := scope.values()
+	query.Set("pipelineIdentifier", pipelineID)
+	query.Set("page", strconv.Itoa(page))
+	query.Set("size", strconv.Itoa(size))
+
+	if filter.FilterType == "" {
+		filter.FilterType = "PipelineExecution"
+	}
+
+	var response models.Response
+	if err := c.do(http.MethodPost, "/pipeline/api/pipelines/execution/summary", query, filter, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// ExecutionDetails fetches the summary of a plan execution along with the
+// graph of every stage and step.
+func (c *HarnessClient) ExecutionDetails(scope HarnessScope, planExecutionID string) (*models.ExecutionDetails, error) {
+	query := scope.values()
+	query.Set("renderFullBottomGraph", "true")
+
+	var response models.ExecutionDetails
+	if err := c.do(http.MethodGet, "/pipeline/api/pipelines/execution/v2/"+url.PathEscape(planExecutionID), query, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// do sends a request to the API and decodes the JSON response into out. body,
+// when not nil, is sent as JSON. Transport errors, 429 and 5xx responses are
+// retried with exponential backoff.
+func (c *HarnessClient) do(method string, path string, query url.Values, body interface{}, out interface{}) error {
+	// path is escaped, e.g. the execution ID of ExecutionDetails.
+	endpoint := *c.baseURL
+	endpoint.RawPath = c.baseURL.EscapedPath() + path
+	unescaped, err := url.PathUnescape(endpoint.RawPath)
+	if err != nil {
+		return err
+	}
+	endpoint.Path = unescaped
+	endpoint.RawQuery = query.Encode()
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.send(method, endpoint, payload, out)
+		if err == nil {
+			return nil
+		}
+		if !retryable(err) || attempt >= c.maxRetries {
+			return err
+		}
+
+		delay := c.retryDelay(attempt, retryAfter)
+		fmt.Printf("| \033[33mHarness request failed (%s), retrying in %s (%d/%d)\033[0m\n", err, delay, attempt+1, c.maxRetries)
+		c.sleep(delay)
+	}
+}
+
+// retryable reports whether a failed request may succeed if sent again:
+// transport errors, 429 and 5xx responses. Anything else, e.g. a response
+// that can't be decoded, would fail the same way.
+func retryable(err error) bool {
+	var apiErr *HarnessAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.retryable()
+	}
+	var urlErr *url.Error
+	var netErr net.Error
+	return errors.As(err, &urlErr) || errors.As(err, &netErr)
+}
+
+// retryDelay returns the delay before the retry following attempt (0 for the
+// first one): the Retry-After delay when the server sent one, retryWait
+// doubled on every attempt otherwise, at most maxRetryWait.
+func (c *HarnessClient) retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = c.retryWait
+		for i := 0; i < attempt && delay < maxRetryWait; i++ {
+			delay *= 2
+		}
+	}
+	if delay > maxRetryWait {
+		delay = maxRetryWait
+	}
+	return delay
+}
+
+// send performs a single attempt of a request. On a non 2xx response it
+// returns a *HarnessAPIError, along with the delay requested by the server
+// through Retry-After, if any.
+func (c *HarnessClient) send(method string, endpoint url.URL, payload []byte, out interface{}) (time.Duration, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, endpoint.String(), reader)
+	if err != nil {
+		return 0, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		// *url.Error already names the method and URL.
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, fmt.Errorf("%s %s: reading response: %w", method, endpoint.Path, err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return parseRetryAfter(res.Header.Get("Retry-After")), &HarnessAPIError{
+			Method:     method,
+			Path:       endpoint.Path,
+			StatusCode: res.StatusCode,
+			Message:    harnessErrorMessage(data),
+		}
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return 0, fmt.Errorf("%s %s: parsing response: %w", method, endpoint.Path, err)
+	}
+	return 0, nil
+}
+
+// harnessErrorMessage extracts the message of a Harness error body, e.g.
+// {"status":"ERROR","code":"INVALID_REQUEST","message":"..."}.
+func harnessErrorMessage(data []byte) string {
+	var body struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Message != "" {
+		if body.Code != "" {
+			return body.Code + ": " + body.Message
+		}
+		return body.Message
+	}
+
+	msg := strings.TrimSpace(string(data))
+	if len(msg) > 200 {
+		msg = msg[:200] + "..."
+	}
+	return msg
+}
+
+// parseRetryAfter parses a Retry-After header, given either in seconds or as
+// an HTTP date. It returns zero when the header is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
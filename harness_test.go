@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var testScope = HarnessScope{AccountID: "acc", OrgID: "org", ProjectID: "proj"}
+
+func newTestClient(t *testing.T, config HarnessClientConfig) *HarnessClient {
+	t.Helper()
+	client, err := NewHarnessClient(config)
+	if err != nil {
+		t.Fatalf("NewHarnessClient: %v", err)
+	}
+	return client
+}
+
+func TestHarnessClientPathPrefix(t *testing.T) {
+	var got *http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		w.Write([]byte(`{"status":"SUCCESS"}`))
+	}))
+	defer server.Close()
+
+	// Self-managed installs serve the API under a prefix such as /gateway.
+	client := newTestClient(t, HarnessClientConfig{BaseURL: server.URL + "/gateway/", APIKey: "pat.secret"})
+	details, err := client.ExecutionDetails(testScope, "exec/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Status != "SUCCESS" {
+		t.Errorf("got status %q, want SUCCESS", details.Status)
+	}
+
+	if want := "/gateway/pipeline/api/pipelines/execution/v2/exec%2F1"; got.URL.EscapedPath() != want {
+		t.Errorf("got path %s, want %s", got.URL.EscapedPath(), want)
+	}
+	query := got.URL.Query()
+	if query.Get("accountIdentifier") != "acc" || query.Get("orgIdentifier") != "org" || query.Get("projectIdentifier") != "proj" {
+		t.Errorf("got query %s, want the scope", got.URL.RawQuery)
+	}
+	if key := got.Header.Get("x-api-key"); key != "pat.secret" {
+		t.Errorf("got x-api-key %q, want pat.secret", key)
+	}
+	if client.BaseURL() != server.URL+"/gateway" {
+		t.Errorf("got base URL %s, want %s/gateway", client.BaseURL(), server.URL)
+	}
+}
+
+func TestHarnessClientInvalidConfig(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "empty.pem")
+	writeFile(t, bundle, "not a certificate")
+
+	for name, config := range map[string]HarnessClientConfig{
+		"relative URL":   {BaseURL: "harness.example.com"},
+		"invalid proxy":  {Proxy: "http://[::1"},
+		"missing bundle": {CABundle: filepath.Join(t.TempDir(), "missing.pem")},
+		"empty bundle":   {CABundle: bundle},
+	} {
+		if _, err := NewHarnessClient(config); err == nil {
+			t.Errorf("%s: NewHarnessClient succeeded, want an error", name)
+		}
+	}
+}
+
+func TestHarnessClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"SUCCESS"}`))
+	}))
+	defer server.Close()
+
+	// The test server's certificate is self-signed, so it is only trusted
+	// through the bundle.
+	untrusted := newTestClient(t, HarnessClientConfig{BaseURL: server.URL})
+	if _, err := untrusted.ExecutionDetails(testScope, "1"); err == nil {
+		t.Fatal("request succeeded without the CA bundle, want a certificate error")
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, certificate, 0644); err != nil {
+		t.Fatal(err)
+	}
+	trusted := newTestClient(t, HarnessClientConfig{BaseURL: server.URL, CABundle: bundle})
+	if _, err := trusted.ExecutionDetails(testScope, "1"); err != nil {
+		t.Fatalf("request failed with the CA bundle: %v", err)
+	}
+}
+
+func TestHarnessClientProxy(t *testing.T) {
+	var proxied *http.Request
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r
+		w.Write([]byte(`{"status":"SUCCESS"}`))
+	}))
+	defer proxy.Close()
+
+	// harness.invalid can't be resolved, the request only succeeds through
+	// the proxy.
+	client := newTestClient(t, HarnessClientConfig{BaseURL: "http://harness.invalid/gateway", Proxy: proxy.URL})
+	if _, err := client.ExecutionDetails(testScope, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if proxied == nil || proxied.Host != "harness.invalid" || proxied.URL.Path != "/gateway/pipeline/api/pipelines/execution/v2/1" {
+		t.Errorf("got proxied request %v, want one for harness.invalid", proxied)
+	}
+}
+
+func TestHarnessClientTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := newTestClient(t, HarnessClientConfig{BaseURL: server.URL, Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	_, err := client.ExecutionDetails(testScope, "1")
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("got error %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("request took %s with a 50ms timeout", elapsed)
+	}
+}
+
+// retryServer answers with the given statuses in turn, then with 200. It
+// counts the requests received.
+type retryServer struct {
+	*httptest.Server
+	count int32
+}
+
+func (s *retryServer) requests() int {
+	return int(atomic.LoadInt32(&s.count))
+}
+
+func newRetryServer(t *testing.T, header http.Header, statuses ...int) *retryServer {
+	t.Helper()
+	server := &retryServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&server.count, 1))
+		if n <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n-1])
+			w.Write([]byte(`{"status":"ERROR","code":"SOME_CODE","message":"something failed"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"SUCCESS"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newRetryClient returns a client recording its retry delays instead of
+// sleeping.
+func newRetryClient(t *testing.T, baseURL string, maxRetries int, delays *[]time.Duration) *HarnessClient {
+	t.Helper()
+	client := newTestClient(t, HarnessClientConfig{BaseURL: baseURL, MaxRetries: maxRetries, RetryWait: 10 * time.Second})
+	client.sleep = func(d time.Duration) { *delays = append(*delays, d) }
+	return client
+}
+
+func TestHarnessClientRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		min, max   time.Duration
+	}{
+		{"seconds", "7", 7 * time.Second, 7 * time.Second},
+		{"HTTP date", time.Now().Add(20 * time.Second).UTC().Format(http.TimeFormat), 15 * time.Second, 20 * time.Second},
+		{"capped", "3600", maxRetryWait, maxRetryWait},
+		{"invalid", "soon", 10 * time.Second, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRetryServer(t, http.Header{"Retry-After": {tt.retryAfter}}, http.StatusTooManyRequests)
+			var delays []time.Duration
+			client := newRetryClient(t, server.URL, 3, &delays)
+
+			if _, err := client.ExecutionDetails(testScope, "1"); err != nil {
+				t.Fatal(err)
+			}
+			if server.requests() != 2 {
+				t.Errorf("got %d requests, want 2", server.requests())
+			}
+			if len(delays) != 1 || delays[0] < tt.min || delays[0] > tt.max {
+				t.Errorf("got delays %v, want one between %s and %s", delays, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestHarnessClientBackoff(t *testing.T) {
+	server := newRetryServer(t, nil, 503, 503, 503, 503, 503, 503)
+	var delays []time.Duration
+	client := newRetryClient(t, server.URL, 5, &delays)
+
+	_, err := client.ExecutionDetails(testScope, "1")
+	if !errors.Is(err, ErrHarnessServer) {
+		t.Fatalf("got error %v, want ErrHarnessServer", err)
+	}
+	if server.requests() != 6 {
+		t.Errorf("got %d requests, want 6", server.requests())
+	}
+	want := []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second, 30 * time.Second}
+	if len(delays) != len(want) {
+		t.Fatalf("got delays %v, want %v", delays, want)
+	}
+	for i := range want {
+		if delays[i] != want[i] {
+			t.Errorf("got delays %v, want %v", delays, want)
+			break
+		}
+	}
+}
+
+func TestHarnessClientErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		want     error
+		requests int
+	}{
+		{http.StatusUnauthorized, ErrHarnessUnauthorized, 1},
+		{http.StatusForbidden, ErrHarnessUnauthorized, 1},
+		{http.StatusNotFound, ErrHarnessNotFound, 1},
+		{http.StatusBadRequest, nil, 1},
+		{http.StatusTooManyRequests, ErrHarnessRateLimited, 3},
+		{http.StatusInternalServerError, ErrHarnessServer, 3},
+		{http.StatusBadGateway, ErrHarnessServer, 3},
+		// Server errors that are not transient are not retried.
+		{http.StatusNotImplemented, ErrHarnessServer, 1},
+	}
+
+	sentinels := []error{ErrHarnessUnauthorized, ErrHarnessNotFound, ErrHarnessRateLimited, ErrHarnessServer}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			server := newRetryServer(t, nil, tt.status, tt.status, tt.status)
+			var delays []time.Duration
+			client := newRetryClient(t, server.URL, 2, &delays)
+
+			_, err := client.ExecutionDetails(testScope, "1")
+			var apiErr *HarnessAPIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status || apiErr.Message != "SOME_CODE: something failed" {
+				t.Fatalf("got error %v, want a HarnessAPIError with status %d", err, tt.status)
+			}
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(err, %v) = %v", sentinel, got)
+				}
+			}
+			if server.requests() != tt.requests {
+				t.Errorf("got %d requests, want %d", server.requests(), tt.requests)
+			}
+		})
+	}
+}
+
+func TestHarnessClientRetryTransportErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		// Drop the connection without answering.
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newRetryClient(t, server.URL, 2, &delays)
+	_, err := client.ExecutionDetails(testScope, "1")
+	if err == nil {
+		t.Fatal("request succeeded, want a transport error")
+	}
+	if requests := atomic.LoadInt32(&requests); requests != 3 || len(delays) != 2 {
+		t.Errorf("got %d requests and %d retries, want 3 and 2", requests, len(delays))
+	}
+}
+
+func TestHarnessClientNoRetryOnDecodeError(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`<html>login</html>`))
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	client := newRetryClient(t, server.URL, 3, &delays)
+	if _, err := client.ExecutionDetails(testScope, "1"); err == nil {
+		t.Fatal("request succeeded, want a parsing error")
+	}
+	if requests := atomic.LoadInt32(&requests); requests != 1 || len(delays) != 0 {
+		t.Errorf("got %d requests and %d retries, want 1 and none", requests, len(delays))
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// noreplyRegexes extract the username from the noreply addresses used by the
+// hosting providers for web commits and private emails.
+var noreplyRegexes = []*regexp.Regexp{
+	// 12345+user@users.noreply.github.com, user@users.noreply.github.com
+	regexp.MustCompile(`^(?:\d+\+)?([\w.-]+)@users\.noreply\.github\.com$`),
+	// 12345-user@users.noreply.gitlab.com
+	regexp.MustCompile(`^(?:\d+-)?([\w.-]+)@users\.noreply\.gitlab\.com$`),
+	// 12345+user@users.noreply.bitbucket.org, user@users.noreply.bitbucket.org
+	regexp.MustCompile(`^(?:\d+\+)?([\w.-]+)@users\.noreply\.bitbucket\.org$`),
+}
+
+// usernameFromEmail returns the username encoded in a noreply email address,
+// or fallback when the address is not one.
+func usernameFromEmail(email string, fallback string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, regex := range noreplyRegexes {
+		if match := regex.FindStringSubmatch(email); match != nil {
+			return match[1]
+		}
+	}
+	return fallback
+}
+
+// mailmapEntry is a single .mailmap line. Commits matching CommitEmail (and
+// CommitName, when set) are attributed to ProperName/ProperEmail; empty
+// proper fields keep the commit's value.
+type mailmapEntry struct {
+	ProperName  string
+	ProperEmail string
+	CommitName  string
+	CommitEmail string
+}
+
+var mailmapLineRegex = regexp.MustCompile(`^([^<]*)<([^>]*)>\s*(?:([^<]*)<([^>]*)>)?\s*$`)
+
+// Mailmap maps commit identities to canonical ones, following the format of
+// git's .mailmap files (see gitmailmap(5)).
+type Mailmap struct {
+	entries []mailmapEntry
+}
+
+// ParseMailmap reads a mailmap file. Comments and malformed lines are ignored.
+func ParseMailmap(r io.Reader) (*Mailmap, error) {
+	mailmap := &Mailmap{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		match := mailmapLineRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		entry := mailmapEntry{
+			ProperName:  strings.TrimSpace(match[1]),
+			ProperEmail: strings.TrimSpace(match[2]),
+		}
+		if match[4] != "" || match[3] != "" {
+			// Proper Name <proper@email> Commit Name <commit@email>
+			entry.CommitName = strings.TrimSpace(match[3])
+			entry.CommitEmail = strings.TrimSpace(match[4])
+		} else {
+			// Proper Name <commit@email>: only the name is replaced.
+			entry.CommitEmail = entry.ProperEmail
+			entry.ProperEmail = ""
+		}
+		mailmap.entries = append(mailmap.entries, entry)
+	}
+
+	return mailmap, scanner.Err()
+}
+
+// LoadMailmap reads a mailmap file from disk. A missing file is not an error
+// and results in an empty mailmap.
+func LoadMailmap(path string) (*Mailmap, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Mailmap{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ParseMailmap(file)
+}
+
+// Resolve returns the canonical identity for a commit name and email. Entries
+// matching both name and email win over entries matching the email only, and
+// later entries win over earlier ones.
+func (m *Mailmap) Resolve(person Person) Person {
+	var emailOnly, nameAndEmail *mailmapEntry
+	for i := range m.entries {
+		entry := &m.entries[i]
+		if !strings.EqualFold(entry.CommitEmail, person.Email) {
+			continue
+		}
+		if entry.CommitName == "" {
+			emailOnly = entry
+		} else if strings.EqualFold(entry.CommitName, person.Name) {
+			nameAndEmail = entry
+		}
+	}
+
+	entry := nameAndEmail
+	if entry == nil {
+		entry = emailOnly
+	}
+	if entry == nil {
+		return person
+	}
+
+	if entry.ProperName != "" {
+		person.Name = entry.ProperName
+	}
+	if entry.ProperEmail != "" {
+		person.Email = entry.ProperEmail
+	}
+	return person
+}
+
+// merge appends the entries of other, so they take precedence over m's.
+func (m *Mailmap) merge(other *Mailmap) {
+	m.entries = append(m.entries, other.entries...)
+}
+
+// IdentityResolver turns the identities found in commits into canonical ones,
+// using the repository's .mailmap and an optional plugin supplied alias file
+// in the same format.
+type IdentityResolver struct {
+	mailmap *Mailmap
+}
+
+// NewIdentityResolver loads <repoDir>/.mailmap and aliasFile (if set). Alias
+// file entries override the repository's.
+func NewIdentityResolver(repoDir string, aliasFile string) (*IdentityResolver, error) {
+	mailmap, err := LoadMailmap(filepath.Join(repoDir, ".mailmap"))
+	if err != nil {
+		return nil, fmt.Errorf("reading .mailmap: %w", err)
+	}
+
+	if aliasFile != "" {
+		if _, err := os.Stat(aliasFile); err != nil {
+			return nil, fmt.Errorf("reading alias file: %w", err)
+		}
+		aliases, err := LoadMailmap(aliasFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading alias file: %w", err)
+		}
+		mailmap.merge(aliases)
+	}
+
+	return &IdentityResolver{mailmap: mailmap}, nil
+}
+
+// Resolve returns the canonical identity of a person.
+func (r *IdentityResolver) Resolve(person Person) Person {
+	person.Name = strings.TrimSpace(person.Name)
+	person.Email = strings.TrimSpace(person.Email)
+	if r == nil || r.mailmap == nil {
+		return person
+	}
+	return r.mailmap.Resolve(person)
+}
+
+// ResolveCommit resolves the author, co-authors and reviewers of a commit.
+func (r *IdentityResolver) ResolveCommit(commit *CommitInfo) {
+	commit.Author = r.Resolve(Person{Name: commit.Name, Email: commit.Email})
+	commit.Username = usernameFromEmail(commit.Author.Email, usernameFromEmail(commit.Email, commit.Username))
+	for i := range commit.CoAuthors {
+		commit.CoAuthors[i] = r.Resolve(commit.CoAuthors[i])
+	}
+	for i := range commit.ReviewedBy {
+		commit.ReviewedBy[i] = r.Resolve(commit.ReviewedBy[i])
+	}
+	for i := range commit.SignedOffBy {
+		commit.SignedOffBy[i] = r.Resolve(commit.SignedOffBy[i])
+	}
+}
+
+// PeopleSet deduplicates people. Two identities are the same person when they
+// share an email address or a name (case insensitive), so once the mailmap
+// has been applied every human ends up as a single entry.
+type PeopleSet struct {
+	people  []Person
+	byEmail map[string]int
+	byName  map[string]int
+}
+
+func NewPeopleSet() *PeopleSet {
+	return &PeopleSet{
+		byEmail: make(map[string]int),
+		byName:  make(map[string]int),
+	}
+}
+
+// Add adds a person, unless it is already known.
+func (s *PeopleSet) Add(person Person) {
+	emailKey := strings.ToLower(person.Email)
+	nameKey := strings.ToLower(person.Name)
+	if emailKey == "" && nameKey == "" {
+		return
+	}
+
+	var i int
+	var found bool
+	if emailKey != "" {
+		i, found = s.byEmail[emailKey]
+	}
+	if !found && nameKey != "" {
+		i, found = s.byName[nameKey]
+	}
+	if !found {
+		i = len(s.people)
+		s.people = append(s.people, person)
+	} else {
+		// Fill in whatever the first identity was missing.
+		if s.people[i].Name == "" {
+			s.people[i].Name = person.Name
+		}
+		if s.people[i].Email == "" {
+			s.people[i].Email = person.Email
+		}
+	}
+
+	if emailKey != "" {
+		s.byEmail[emailKey] = i
+	}
+	if nameKey != "" {
+		s.byName[nameKey] = i
+	}
+}
+
+// People returns the distinct people sorted by name.
+func (s *PeopleSet) People() []Person {
+	people := append([]Person(nil), s.people...)
+	sort.SliceStable(people, func(i, j int) bool {
+		return strings.ToLower(people[i].Name) < strings.ToLower(people[j].Name)
+	})
+	return people
+}
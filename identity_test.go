@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testMailmap = `# The four forms of gitmailmap(5).
+Ada Lovelace <ada@example.com>
+<grace@example.com> <ghopper@old.example.com>
+Alan Turing <alan@example.com> <aturing@old.example.com>
+Edsger Dijkstra <edsger@example.com> Ed <ed@shared.example.com>
+malformed line without email
+Shared Account <ed@shared.example.com> # email only, for everyone else
+`
+
+func TestMailmapResolve(t *testing.T) {
+	mailmap, err := ParseMailmap(strings.NewReader(testMailmap))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		person Person
+		want   Person
+	}{
+		{
+			name:   "proper name",
+			person: Person{Name: "ada", Email: "ada@example.com"},
+			want:   Person{Name: "Ada Lovelace", Email: "ada@example.com"},
+		},
+		{
+			name:   "proper email",
+			person: Person{Name: "Grace Hopper", Email: "ghopper@old.example.com"},
+			want:   Person{Name: "Grace Hopper", Email: "grace@example.com"},
+		},
+		{
+			name:   "proper name and email",
+			person: Person{Name: "turing", Email: "ATuring@old.example.com"},
+			want:   Person{Name: "Alan Turing", Email: "alan@example.com"},
+		},
+		{
+			name:   "commit name and email",
+			person: Person{Name: "ed", Email: "ed@shared.example.com"},
+			want:   Person{Name: "Edsger Dijkstra", Email: "edsger@example.com"},
+		},
+		{
+			name:   "email only entry for another name",
+			person: Person{Name: "Someone", Email: "ed@shared.example.com"},
+			want:   Person{Name: "Shared Account", Email: "ed@shared.example.com"},
+		},
+		{
+			name:   "unknown",
+			person: Person{Name: "Linus", Email: "linus@example.com"},
+			want:   Person{Name: "Linus", Email: "linus@example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mailmap.Resolve(tt.person); got != tt.want {
+				t.Errorf("Resolve(%+v) = %+v, want %+v", tt.person, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdentityResolverAliasFile(t *testing.T) {
+	repoDir := t.TempDir()
+	writeFile(t, filepath.Join(repoDir, ".mailmap"), "Ada Lovelace <ada@example.com> <ada@home.example.com>\nGrace Hopper <grace@example.com>\n")
+	aliasFile := filepath.Join(t.TempDir(), "aliases")
+	writeFile(t, aliasFile, "Ada King <ada@example.com> <ada@home.example.com>\n")
+
+	resolver, err := NewIdentityResolver(repoDir, aliasFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		person Person
+		want   Person
+	}{
+		// The alias file wins over the repository's .mailmap.
+		{Person{Name: "ada", Email: "ada@home.example.com"}, Person{Name: "Ada King", Email: "ada@example.com"}},
+		// Entries only found in the .mailmap still apply.
+		{Person{Name: "grace", Email: " grace@example.com "}, Person{Name: "Grace Hopper", Email: "grace@example.com"}},
+	}
+	for _, tt := range tests {
+		if got := resolver.Resolve(tt.person); got != tt.want {
+			t.Errorf("Resolve(%+v) = %+v, want %+v", tt.person, got, tt.want)
+		}
+	}
+
+	if _, err := NewIdentityResolver(repoDir, filepath.Join(repoDir, "missing")); err == nil {
+		t.Error("NewIdentityResolver succeeded with a missing alias file, want an error")
+	}
+
+	// A repository without .mailmap resolves nothing.
+	empty, err := NewIdentityResolver(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	person := Person{Name: "ada", Email: "ada@home.example.com"}
+	if got := empty.Resolve(person); got != person {
+		t.Errorf("Resolve(%+v) = %+v without mailmap", person, got)
+	}
+}
+
+func TestUsernameFromEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  string
+	}{
+		{"12345+octocat@users.noreply.github.com", "octocat"},
+		{"octocat@users.noreply.github.com", "octocat"},
+		{"OctoCat@Users.NoReply.GitHub.com", "octocat"},
+		{"12345-tanuki@users.noreply.gitlab.com", "tanuki"},
+		{"12345+bucket.user@users.noreply.bitbucket.org", "bucket.user"},
+		{"ada@example.com", "fallback"},
+		{"octocat@noreply.github.com", "fallback"},
+		{"", "fallback"},
+	}
+
+	for _, tt := range tests {
+		if got := usernameFromEmail(tt.email, "fallback"); got != tt.want {
+			t.Errorf("usernameFromEmail(%q) = %q, want %q", tt.email, got, tt.want)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"time"
+
+	"commit-insights/internal/report"
+)
+
+// InsightsSchemaVersion is the version of the insights document, described by
+// schemas/insights-v1.schema.json. Fields may be added within a version,
+// renaming or removing one requires a new version.
+const InsightsSchemaVersion = "1"
+
+// now returns the generation time of the insights, pinned by the tests.
+var now = time.Now
+
+// Insights is the machine-readable counterpart of the HTML report, for the
+// steps and dashboards consuming the results of the plugin.
+type Insights struct {
+	SchemaVersion string               `json:"schemaVersion"`
+	GeneratedAt   string               `json:"generatedAt"`
+	BuildCreated  string               `json:"buildCreated,omitempty"`
+	Repository    InsightsRepository   `json:"repository"`
+	Pipeline      *InsightsPipeline    `json:"pipeline,omitempty"`
+	Range         InsightsRange        `json:"range"`
+	Stats         InsightsStats        `json:"stats"`
+	Authors       []InsightsAuthor     `json:"authors"`
+	Commits       []InsightsCommit     `json:"commits"`
+	Files         []InsightsFile       `json:"files"`
+	MergedPRs     []InsightsMergeGroup `json:"mergedPRs"`
+}
+
+type InsightsRepository struct {
+	Name      string `json:"name"`
+	Branch    string `json:"branch"`
+	BuildType string `json:"buildType"`
+}
+
+// InsightsPipeline is the baseline execution the range starts from, along
+// with a link to the current execution.
+type InsightsPipeline struct {
+	Name         string `json:"name"`
+	Status       string `json:"status"`
+	StartedTime  string `json:"startedTime"`
+	Duration     string `json:"duration"`
+	ExecutionURL string `json:"executionURL,omitempty"`
+}
+
+// InsightsRange is the commit range of the report. Refs lists the refs
+// updated by a push payload, each with its own range.
+type InsightsRange struct {
+	InsightsLogRange
+	Refs []InsightsRef `json:"refs,omitempty"`
+}
+
+// InsightsLogRange is a range as logged, with the resolved strategy.
+type InsightsLogRange struct {
+	Strategy   string `json:"strategy"`
+	Old        string `json:"old"`
+	New        string `json:"new"`
+	ExcludeOld bool   `json:"excludeOld"`
+}
+
+type InsightsRef struct {
+	Ref     string `json:"ref"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Old     string `json:"old,omitempty"`
+	New     string `json:"new,omitempty"`
+	Release bool   `json:"release"`
+	// Range is missing for deleted refs.
+	Range *InsightsLogRange `json:"range,omitempty"`
+}
+
+type InsightsStats struct {
+	Commits      int `json:"commits"`
+	MergeCommits int `json:"mergeCommits"`
+	MergedPRs    int `json:"mergedPRs"`
+	Authors      int `json:"authors"`
+	FilesChanged int `json:"filesChanged"`
+	Additions    int `json:"additions"`
+	Deletions    int `json:"deletions"`
+	BinaryFiles  int `json:"binaryFiles"`
+}
+
+type InsightsPerson struct {
+	Name     string `json:"name"`
+	Email    string `json:"email,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// InsightsAuthor is a person credited on commits, as author or co-author.
+type InsightsAuthor struct {
+	InsightsPerson
+	Commits   int `json:"commits"`
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+}
+
+type InsightsCommit struct {
+	Hash        string               `json:"hash"`
+	Title       string               `json:"title"`
+	Body        string               `json:"body,omitempty"`
+	Author      InsightsPerson       `json:"author"`
+	Committer   InsightsPerson       `json:"committer"`
+	Time        string               `json:"time"`
+	Parents     []string             `json:"parents"`
+	Merge       bool                 `json:"merge"`
+	CoAuthors   []InsightsPerson     `json:"coAuthors,omitempty"`
+	ReviewedBy  []InsightsPerson     `json:"reviewedBy,omitempty"`
+	SignedOffBy []InsightsPerson     `json:"signedOffBy,omitempty"`
+	Additions   int                  `json:"additions"`
+	Deletions   int                  `json:"deletions"`
+	Changes     []InsightsFileChange `json:"changes"`
+}
+
+type InsightsFileChange struct {
+	Path       string `json:"path"`
+	Status     string `json:"status"`
+	OldPath    string `json:"oldPath,omitempty"`
+	Similarity int    `json:"similarity,omitempty"`
+	Additions  int    `json:"additions"`
+	Deletions  int    `json:"deletions"`
+	Binary     bool   `json:"binary"`
+}
+
+// InsightsFile sums up the changes of a file over the whole range. Status is
+// the status of its latest change.
+type InsightsFile struct {
+	Path      string   `json:"path"`
+	Status    string   `json:"status"`
+	Additions int      `json:"additions"`
+	Deletions int      `json:"deletions"`
+	Binary    bool     `json:"binary"`
+	Commits   []string `json:"commits"`
+}
+
+type InsightsMergeGroup struct {
+	Merge   string   `json:"merge"`
+	Title   string   `json:"title"`
+	Commits []string `json:"commits"`
+}
+
+// BuildInsights builds the insights document of a report.
+func BuildInsights(r *report.Report) *Insights {
+	insights := &Insights{
+		SchemaVersion: InsightsSchemaVersion,
+		GeneratedAt:   now().UTC().Format(time.RFC3339),
+		Repository: InsightsRepository{
+			Name:      r.Repository.Name,
+			Branch:    r.Repository.Branch,
+			BuildType: r.Repository.BuildType,
+		},
+		Range: InsightsRange{
+			InsightsLogRange: insightsLogRange(r.Range.LogRange),
+		},
+		Authors:   []InsightsAuthor{},
+		Commits:   []InsightsCommit{},
+		Files:     []InsightsFile{},
+		MergedPRs: []InsightsMergeGroup{},
+	}
+
+	if !r.BuildCreated.IsZero() {
+		insights.BuildCreated = r.BuildCreated.UTC().Format(time.RFC3339)
+	}
+
+	if baseline := r.Baseline; baseline != nil {
+		insights.Pipeline = &InsightsPipeline{
+			Name:         baseline.Name,
+			Status:       baseline.Status,
+			StartedTime:  baseline.StartedTime,
+			Duration:     baseline.Duration,
+			ExecutionURL: r.ExecutionURL,
+		}
+	}
+
+	for _, ref := range r.Range.Refs {
+		insightsRef := InsightsRef{
+			Ref:     ref.Ref,
+			Name:    ref.Name,
+			Type:    ref.Type,
+			Action:  ref.Action,
+			Old:     ref.OldSHA,
+			New:     ref.NewSHA,
+			Release: ref.Release,
+		}
+		if ref.Range != nil {
+			logRange := insightsLogRange(*ref.Range)
+			insightsRef.Range = &logRange
+		}
+		insights.Range.Refs = append(insights.Range.Refs, insightsRef)
+	}
+
+	for _, author := range r.Authors() {
+		insights.Authors = append(insights.Authors, InsightsAuthor{
+			InsightsPerson: insightsPerson(author.Person),
+			Commits:        author.Commits,
+			Additions:      author.Additions,
+			Deletions:      author.Deletions,
+		})
+	}
+
+	for _, commit := range r.Commits {
+		entry := InsightsCommit{
+			Hash:        commit.Hash,
+			Title:       commit.Title,
+			Body:        commit.Body,
+			Author:      insightsPerson(commit.Author),
+			Committer:   insightsPerson(commit.Committer),
+			Time:        insightsTime(commit.Time),
+			Parents:     commit.Parents,
+			Merge:       commit.Merge,
+			CoAuthors:   insightsPeople(commit.CoAuthors),
+			ReviewedBy:  insightsPeople(commit.ReviewedBy),
+			SignedOffBy: insightsPeople(commit.SignedOffBy),
+			Additions:   commit.Additions,
+			Deletions:   commit.Deletions,
+			Changes:     []InsightsFileChange{},
+		}
+		if entry.Parents == nil {
+			entry.Parents = []string{}
+		}
+		for _, change := range commit.Changes {
+			entry.Changes = append(entry.Changes, insightsFileChange(change))
+		}
+		insights.Commits = append(insights.Commits, entry)
+	}
+
+	for _, file := range r.Files() {
+		insights.Files = append(insights.Files, InsightsFile{
+			Path:      file.Path,
+			Status:    file.Status,
+			Additions: file.Additions,
+			Deletions: file.Deletions,
+			Binary:    file.Binary,
+			Commits:   file.Commits,
+		})
+	}
+
+	for _, pr := range r.MergedPRs {
+		entry := InsightsMergeGroup{Merge: pr.Merge.Hash, Title: pr.Merge.Title, Commits: []string{}}
+		for _, commit := range pr.Commits {
+			entry.Commits = append(entry.Commits, commit.Hash)
+		}
+		insights.MergedPRs = append(insights.MergedPRs, entry)
+	}
+
+	stats := r.Stats()
+	insights.Stats = InsightsStats{
+		Commits:      stats.Commits,
+		MergeCommits: stats.MergeCommits,
+		MergedPRs:    stats.MergedPRs,
+		Authors:      stats.Authors,
+		FilesChanged: stats.FilesChanged,
+		Additions:    stats.Additions,
+		Deletions:    stats.Deletions,
+		BinaryFiles:  stats.BinaryFiles,
+	}
+
+	return insights
+}
+
+func insightsPerson(person report.Person) InsightsPerson {
+	return InsightsPerson{Name: person.Name, Email: person.Email, Username: person.Username}
+}
+
+func insightsLogRange(logRange report.LogRange) InsightsLogRange {
+	return InsightsLogRange{
+		Strategy:   logRange.Strategy,
+		Old:        logRange.Old,
+		New:        logRange.New,
+		ExcludeOld: logRange.ExcludeOld,
+	}
+}
+
+func insightsFileChange(change report.FileChange) InsightsFileChange {
+	return InsightsFileChange{
+		Path:       change.Path,
+		Status:     change.Status,
+		OldPath:    change.OldPath,
+		Similarity: change.Similarity,
+		Additions:  change.Additions,
+		Deletions:  change.Deletions,
+		Binary:     change.Binary,
+	}
+}
+
+func insightsPeople(people []report.Person) []InsightsPerson {
+	var result []InsightsPerson
+	for _, person := range people {
+		result = append(result, insightsPerson(person))
+	}
+	return result
+}
+
+// insightsTime formats a commit time as RFC 3339, in UTC.
+func insightsTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
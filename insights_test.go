@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"commit-insights/internal/report"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+const insightsSchemaPath = "schemas/insights-v1.schema.json"
+
+// goldenReport sets every field of the insights document, so that the golden
+// file covers the whole schema.
+func goldenReport() *report.Report {
+	ada := report.Person{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada"}
+	grace := report.Person{Name: "Grace Hopper", Email: "grace@example.com"}
+	github := report.Person{Name: "GitHub", Email: "noreply@github.com"}
+	paris := time.FixedZone("CET", 3600)
+
+	feature := report.CommitEntry{
+		Hash:        "5b2e0c4e1d7a3f6b8c9d0e1f2a3b4c5d6e7f8091",
+		Title:       "Rename notes",
+		Body:        "Co-authored-by: Grace Hopper <grace@example.com>",
+		Author:      ada,
+		Committer:   ada,
+		Time:        time.Date(2024, 3, 4, 10, 15, 0, 0, paris),
+		Parents:     []string{"9049f1265b7d61be4a8904a9a27120d2064dab3b"},
+		CoAuthors:   []report.Person{grace},
+		ReviewedBy:  []report.Person{{Name: "Alan Turing", Email: "alan@example.com"}},
+		SignedOffBy: []report.Person{ada},
+		Additions:   1,
+		Changes: []report.FileChange{
+			{Path: "docs.txt", OldPath: "notes.txt", Status: "R", Similarity: 94, Additions: 1},
+			{Path: "logo.png", Status: "A", Binary: true},
+		},
+	}
+	merge := report.CommitEntry{
+		Hash:      "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+		Title:     "Merge pull request #42 from acme/feature",
+		Author:    grace,
+		Committer: github,
+		Time:      time.Date(2024, 3, 4, 11, 0, 0, 0, time.UTC),
+		Parents:   []string{"9049f1265b7d61be4a8904a9a27120d2064dab3b", feature.Hash},
+		Merge:     true,
+	}
+	cleanup := report.CommitEntry{
+		Hash:      "6113728f27ae82c7b1a177c8d03f9e96e0adf246",
+		Title:     "Remove the old notes",
+		Author:    grace,
+		Committer: grace,
+		Time:      time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC),
+		Parents:   []string{merge.Hash},
+		Deletions: 3,
+		Changes:   []report.FileChange{{Path: "notes.old", Status: "D", Deletions: 3}},
+	}
+
+	mainRange := report.LogRange{Strategy: RangeCommits, Old: "9049f1265b7d61be4a8904a9a27120d2064dab3b", New: cleanup.Hash, ExcludeOld: true}
+	return &report.Report{
+		Repository: report.Repository{Name: "commit-insights", Branch: "main", BuildType: "push"},
+		Baseline: &report.PipelineRef{
+			Name:        "Build",
+			Status:      "Success",
+			StartedTime: "2024-03-03 18:00:00",
+			Duration:    "4m 12s",
+		},
+		ExecutionURL: "https://app.harness.io/ng/account/acme/executions/abc123/pipeline",
+		BuildCreated: time.Date(2024, 3, 4, 13, 0, 0, 0, paris),
+		Range: report.Range{
+			LogRange: mainRange,
+			Refs: []report.RefUpdate{
+				{Ref: "refs/heads/main", Name: "main", Type: "branch", Action: "updated", OldSHA: "9049f1265b7d61be4a8904a9a27120d2064dab3b", NewSHA: cleanup.Hash, Range: &mainRange},
+				{Ref: "refs/tags/v1.0.0", Name: "v1.0.0", Type: "tag", Action: "created", NewSHA: cleanup.Hash, Release: true, Range: &report.LogRange{Strategy: RangeCommits, Old: cleanup.Hash, New: cleanup.Hash}},
+				{Ref: "refs/heads/feature", Name: "feature", Type: "branch", Action: "deleted", OldSHA: feature.Hash},
+			},
+		},
+		Commits:   []report.CommitEntry{cleanup, merge, feature},
+		MergedPRs: []report.MergedPR{{Merge: merge, Commits: []report.CommitEntry{feature}}},
+	}
+}
+
+func TestInsightsGolden(t *testing.T) {
+	defer func(original func() time.Time) { now = original }(now)
+	now = func() time.Time { return time.Date(2024, 3, 4, 14, 30, 0, 0, time.UTC) }
+
+	got, err := jsonRenderer{}.Render(goldenReport(), RenderOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "insights.golden.json")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("insights differ from %s, run go test -update if the change is intended:\n%s", golden, got)
+	}
+
+	schema := loadSchema(t)
+	var document interface{}
+	if err := json.Unmarshal(got, &document); err != nil {
+		t.Fatal(err)
+	}
+	validator := &schemaValidator{root: schema}
+	validator.validate(schema, document, "")
+	for _, problem := range validator.problems {
+		t.Errorf("%s does not match %s: %s", golden, insightsSchemaPath, problem)
+	}
+}
+
+// TestInsightsSchemaFields checks that the schema describes every field of
+// the Insights structs, and only those, and that the fields without
+// omitempty are the required ones.
+func TestInsightsSchemaFields(t *testing.T) {
+	schema := loadSchema(t)
+	validator := &schemaValidator{root: schema}
+	validator.compareStruct(reflect.TypeOf(Insights{}), schema, "")
+	for _, problem := range validator.problems {
+		t.Error(problem)
+	}
+}
+
+func loadSchema(t *testing.T) map[string]interface{} {
+	t.Helper()
+	data, err := os.ReadFile(insightsSchemaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// schemaValidator implements the JSON schema keywords used by the insights
+// schema. Unlike a plain JSON schema validator, it refuses the properties the
+// schema does not describe.
+type schemaValidator struct {
+	root     map[string]interface{}
+	problems []string
+}
+
+func (v *schemaValidator) errorf(path string, format string, args ...interface{}) {
+	if path == "" {
+		path = "/"
+	}
+	v.problems = append(v.problems, path+": "+fmt.Sprintf(format, args...))
+}
+
+// resolve follows the $ref of a schema, e.g. #/$defs/sha.
+func (v *schemaValidator) resolve(schema map[string]interface{}) map[string]interface{} {
+	ref, ok := schema["$ref"].(string)
+	if !ok {
+		return schema
+	}
+	var node interface{} = v.root
+	for _, name := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, _ := node.(map[string]interface{})
+		node = object[name]
+	}
+	resolved, ok := node.(map[string]interface{})
+	if !ok {
+		panic("unresolved schema reference " + ref)
+	}
+	return v.resolve(resolved)
+}
+
+// properties returns the properties and required properties of an object
+// schema, including those of its allOf schemas.
+func (v *schemaValidator) properties(schema map[string]interface{}) (map[string]map[string]interface{}, map[string]bool) {
+	schema = v.resolve(schema)
+	properties := make(map[string]map[string]interface{})
+	required := make(map[string]bool)
+	for name, property := range asObject(schema["properties"]) {
+		properties[name] = property.(map[string]interface{})
+	}
+	for _, name := range asArray(schema["required"]) {
+		required[name.(string)] = true
+	}
+	for _, sub := range asArray(schema["allOf"]) {
+		subProperties, subRequired := v.properties(sub.(map[string]interface{}))
+		for name, property := range subProperties {
+			properties[name] = property
+		}
+		for name := range subRequired {
+			required[name] = true
+		}
+	}
+	return properties, required
+}
+
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) {
+	schema = v.resolve(schema)
+
+	if constant, ok := schema["const"]; ok && !reflect.DeepEqual(value, constant) {
+		v.errorf(path, "got %v, want %v", value, constant)
+	}
+	if enum, ok := schema["enum"]; ok {
+		found := false
+		for _, allowed := range asArray(enum) {
+			found = found || reflect.DeepEqual(value, allowed)
+		}
+		if !found {
+			v.errorf(path, "got %v, want one of %v", value, enum)
+		}
+	}
+
+	switch schema["type"] {
+	case nil:
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			v.errorf(path, "got %T, want a string", value)
+			return
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				v.errorf(path, "%q is not a date-time", s)
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			v.errorf(path, "got %v, want an integer", value)
+			return
+		}
+		if minimum, ok := schema["minimum"].(float64); ok && n < minimum {
+			v.errorf(path, "%v is below the minimum %v", n, minimum)
+		}
+		if maximum, ok := schema["maximum"].(float64); ok && n > maximum {
+			v.errorf(path, "%v is above the maximum %v", n, maximum)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.errorf(path, "got %T, want a boolean", value)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			v.errorf(path, "got %T, want an array", value)
+			return
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				v.validate(itemSchema, item, path+"/"+strconv.Itoa(i))
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			v.errorf(path, "got %T, want an object", value)
+			return
+		}
+		properties, required := v.properties(schema)
+		for name := range required {
+			if _, ok := object[name]; !ok {
+				v.errorf(path, "missing required property %q", name)
+			}
+		}
+		for _, name := range sortedKeys(object) {
+			property, ok := properties[name]
+			if !ok {
+				v.errorf(path, "property %q is not in the schema", name)
+				continue
+			}
+			v.validate(property, object[name], path+"/"+name)
+		}
+	default:
+		v.errorf(path, "unsupported schema type %v", schema["type"])
+	}
+}
+
+// compareStruct compares the JSON fields of a type with the properties of
+// its schema.
+func (v *schemaValidator) compareStruct(typ reflect.Type, schema map[string]interface{}, path string) {
+	schema = v.resolve(schema)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Slice:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			v.compareStruct(typ.Elem(), items, path+"/items")
+		} else {
+			v.errorf(path, "the schema of %s has no items", typ)
+		}
+	case reflect.Struct:
+		properties, required := v.properties(schema)
+		fields := jsonFields(typ)
+		for _, name := range sortedKeys(fields) {
+			field := fields[name]
+			property, ok := properties[name]
+			if !ok {
+				v.errorf(path, "field %s.%s (%q) is not in the schema", typ.Name(), field.Name, name)
+				continue
+			}
+			omitempty := strings.Contains(field.Tag.Get("json"), ",omitempty")
+			if required[name] == omitempty {
+				v.errorf(path, "field %s.%s (%q): omitempty is %v, but required in the schema is %v", typ.Name(), field.Name, name, omitempty, required[name])
+			}
+			v.compareStruct(field.Type, property, path+"/"+name)
+		}
+		for name := range properties {
+			if _, ok := fields[name]; !ok {
+				v.errorf(path, "property %q of the schema has no field in %s", name, typ.Name())
+			}
+		}
+	}
+}
+
+// jsonFields returns the fields of a struct by JSON name, flattening
+// embedded structs like encoding/json does.
+func jsonFields(typ reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous {
+			for name, embedded := range jsonFields(field.Type) {
+				fields[name] = embedded
+			}
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+func asObject(value interface{}) map[string]interface{} {
+	object, _ := value.(map[string]interface{})
+	return object
+}
+
+func asArray(value interface{}) []interface{} {
+	array, _ := value.([]interface{})
+	return array
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -11,150 +11,27 @@ import (
 	"time"
 )
 
-// GenerateDashboardHTML generates HTML for the dashboard based on a JSON structure.
-func GenerateDashboardHTML(pipeline models.Pipeline) (string, error) {
-	fmt.Println("|---------------------------------------------")
-	fmt.Println("| \033[1;36mGenerating dashboard...\033[0m")
-	fmt.Println("|---------------------------------------------")
-
-	fmt.Println("Pipeline: ", pipeline)
-
-	const customDateFormat = "2006-01-02 15:04:05 -0700 MST"
-	var err error
-	startedTime, err := time.Parse(customDateFormat, pipeline.StartedTime)
-
-	if err != nil {
-		log.Println("Error parsing time: ", err)
-	}
-	pipeline.StartedTime = startedTime.Format("Jan 02 15:04:05 MST")
-
-	sort.Slice(pipeline.Stages, func(i, j int) bool {
-		startTimeI, err := time.Parse(customDateFormat, pipeline.Stages[i].StartTs)
-		if err != nil && pipeline.Stages[i].StartTs != "" {
-			log.Println("Error parsing time: ", err)
-		}
-		startTimeJ, err := time.Parse(customDateFormat, pipeline.Stages[j].StartTs)
-		if err != nil && pipeline.Stages[j].StartTs != "" {
-			log.Println("Error parsing time: ", err)
-		}
-		return startTimeI.Before(startTimeJ)
-	})
-
-	for i := range pipeline.Stages {
-		sort.Slice(pipeline.Stages[i].Steps, func(m, n int) bool {
-			startTimeM, errM := time.Parse(customDateFormat, pipeline.Stages[i].Steps[m].StartTs)
-			startTimeN, errN := time.Parse(customDateFormat, pipeline.Stages[i].Steps[n].StartTs)
-
-			if errM != nil && pipeline.Stages[i].Steps[m].Status != "Skipped" {
-				log.Println("Status: ", pipeline.Stages[i].Steps[m].Status)
-				log.Println("Error parsing time: ", errM)
-			}
-			if errN != nil && pipeline.Stages[i].Steps[n].Status != "Skipped" {
-				log.Println("Status: ", pipeline.Stages[i].Steps[n].Status)
-				log.Println("Error parsing time: ", errN)
-			}
-
-			if pipeline.Stages[i].Steps[m].Status == "Skipped" {
-				return false
-			}
-			if pipeline.Stages[i].Steps[n].Status == "Skipped" {
-				return true
-			}
-
-			return startTimeM.Before(startTimeN)
-		})
-	}
-
-	for i := range pipeline.Stages {
-		startTime, err := time.Parse(customDateFormat, pipeline.Stages[i].StartTs)
-		if err != nil && pipeline.Stages[i].StartTs != "" {
-			log.Println("Error parsing time: ", err)
-		}
-		endTime, err := time.Parse(customDateFormat, pipeline.Stages[i].EndTs)
-		if err != nil && pipeline.Stages[i].EndTs != "" {
-			log.Println("Error parsing time: ", err)
-		}
-		duration := endTime.Sub(startTime)
-
-		if duration < time.Minute {
-			pipeline.Stages[i].Duration = fmt.Sprintf("%.0f seconds", duration.Seconds())
-		} else if duration < time.Hour {
-			pipeline.Stages[i].Duration = fmt.Sprintf("%.0f minutes", duration.Minutes())
-		} else if duration < time.Hour*24 {
-			hours := int(duration.Hours())
-			minutes := int(duration.Minutes()) % 60
-			pipeline.Stages[i].Duration = fmt.Sprintf("%d hours %d minutes", hours, minutes)
-		} else if duration > time.Hour*24*365 {
-			pipeline.Stages[i].Duration = fmt.Sprintln("None")
-		} else {
-			days := int(duration.Hours()) / 24
-			hours := int(duration.Hours()) % 24
-			pipeline.Stages[i].Duration = fmt.Sprintf("%d days %d hours", days, hours)
-
-		}
-		// convert start and end ts to shot date time and timezone , and year with 2 digits
-		startTime = startTime.Local()
-		pipeline.Stages[i].StartTs = startTime.Format("Jan 02 15:04:05 MST")
-		endTime = endTime.Local()
-		pipeline.Stages[i].EndTs = endTime.Format("Jan 02 15:04:05 MST")
-
-		pipeline.Stages[i].Duration = duration.String()
-
-		for j := range pipeline.Stages[i].Steps {
-			startTime, err := time.Parse(customDateFormat, pipeline.Stages[i].Steps[j].StartTs)
-			if err != nil && pipeline.Stages[i].Steps[j].StartTs != "" {
-				log.Println("Error parsing time: ", err)
-			}
-			endTime, err := time.Parse(customDateFormat, pipeline.Stages[i].Steps[j].EndTs)
-			if err != nil && pipeline.Stages[i].Steps[j].EndTs != "" {
-				log.Println("Error parsing time: ", err)
-			}
-
-			pipeline.Stages[i].Steps[j].StartTs = startTime.Format("Jan02 15:04:05 MST")
-			pipeline.Stages[i].Steps[j].EndTs = endTime.Format("Jan 02 15:04:05 MST")
-
-			duration := endTime.Sub(startTime)
+// Dashboard is a pipeline execution shown under a title, e.g. "Baseline
+// Execution".
+type Dashboard struct {
+	Title    string
+	Pipeline models.Pipeline
+}
 
-			if duration < time.Minute {
-				pipeline.Stages[i].Steps[j].Duration = fmt.Sprintf("%.0f seconds", duration.Seconds())
-			} else if duration < time.Hour {
-				pipeline.Stages[i].Steps[j].Duration = fmt.Sprintf("%.0f minutes", duration.Minutes())
-			} else if duration < time.Hour*24 {
-				days := int(duration.Hours()) / 24
-				pipeline.Stages[i].Steps[j].Duration = fmt.Sprintf("%d days", days)
-			} else if duration > time.Hour*24*365 {
-				pipeline.Stages[i].Steps[j].Duration = fmt.Sprintf("None")
-			} else {
-				days := int(duration.Hours()) / 24
-				hours := int(duration.Hours()) % 24
-				pipeline.Stages[i].Steps[j].Duration = fmt.Sprintf("%d days %d hours", days, hours)
-			}
-		}
+// customDateFormat is the format of the pipeline, stage and step timestamps,
+// i.e. time.Time.String().
+const customDateFormat = "2006-01-02 15:04:05 -0700 MST"
 
-	}
-
-	const htmlTemplate = `
-	<!DOCTYPE html>
-	<html>
-	<head>
-		<style>
-		body {
-			font-family: Arial, sans-serif;
-			margin: 0;
-			background-color: #f0f0f0;
-			display: flex;
-			flex-direction: column;
-			align-items: center;
-		}
+// DashboardStyle is the CSS of the dashboard sections. It does not style the
+// page itself, so it can be added to the style of another page.
+const DashboardStyle = `
 		.pipeline-container {
 			background-color: #fff;
 			border-radius: 10px;
 			box-shadow: 0 0 10px rgba(0,0,0,0.1);
 			overflow-x: auto;
 			overflow-y: auto;
-			max-width: 90%;
-			max-height: 90vh;
-			margin: 10px 20px;
+			margin: 10px 0;
 			padding: 20px;
 		}
 		.pipeline-title {
@@ -212,34 +89,28 @@ func GenerateDashboardHTML(pipeline models.Pipeline) (string, error) {
 			width: 100%; /* Set to 100% to occupy the full width of the step container */
 			max-width: 100%; /* Set to 100% to prevent horizontal stretching */
 		}
-		
-		
-		.green {
-			background-color: rgba(40, 167, 69, 0.1);
-		}
-		.red {
-			background-color: rgba(203, 36, 49, 0.1);
-		}
-		.orange {
-			background-color: rgba(227, 98, 9, 0.1);
-		}
-        .Success {
+        .Success, .IgnoreFailed {
             background-color: rgba(76, 175, 80, 0.5); /* Green with Transparency */
         }
-        .Failed {
+        .Failed, .Errored, .Expired, .ApprovalRejected {
             background-color: rgba(255, 87, 51, 0.5); /* Red with Transparency */
         }
-        .Skipped {
+        .Skipped, .NotStarted, .Queued {
             background-color: rgba(169, 169, 169, 0.5); /* Gray with Transparency */
         }
 		.Aborted {
 			background-color: rgba(255, 87, 51, 0.5); /* Red with Transparency */
 		}
-		</style>
-	</head>
-	<body>
+		.Running, .AsyncWaiting, .ApprovalWaiting, .InterventionWaiting {
+			background-color: rgba(0, 171, 227, 0.3); /* Blue with Transparency */
+		}
+`
+
+const dashboardSection = `
+	{{ range . }}
 	<div class="pipeline-container">
-		<div class="pipeline-title">{{ .Name }} - Status: {{ .Status }}</div>
+		<div class="pipeline-title">{{ .Title }}: {{ .Pipeline.Name }} - Status: {{ .Pipeline.Status }}</div>
+		{{ with .Pipeline }}
 		<div class="pipeline-info">
 			Started Time: {{ .StartedTime }}<br>
 			Duration: {{ .Duration }}<br>
@@ -249,48 +120,192 @@ func GenerateDashboardHTML(pipeline models.Pipeline) (string, error) {
 		</div>
 		<div class="stage-container">
 			{{ range .Stages }}
-			<div class="stage">
+			<div class="stage {{ .Status }}">
 				<h4>{{ .Name }}</h4>
-				<p>Duration: {{ .Duration }}</p>
+				{{ if .Duration }}<p>Duration: {{ .Duration }}</p>{{ end }}
 				<div class="step-container">
 					{{ range .Steps }}
 					<div class="step {{ .Status }}">
 						<h4>{{ .Name }}</h4>
 						{{ if .Message }}<p>Message: {{ .Message }}</p>{{ end }}
-						{{ if ne .Status "skipped" }}<p>Duration: {{ .Duration }}</p>{{ end }}
-						{{ if eq .Status "failure" }}<p>Error: {{ .FailureInfo.Message }}</p><p>Failure Types: {{ range .FailureInfo.FailureTypeList }}{{ . }} {{ end }}</p>{{ end }}
+						{{ if and .Duration (ne .Status "Skipped") }}<p>Duration: {{ .Duration }}</p>{{ end }}
+						{{ if .FailureInfo.Message }}<p>Error: {{ .FailureInfo.Message }}</p>{{ if .FailureInfo.FailureTypeList }}<p>Failure Types: {{ range .FailureInfo.FailureTypeList }}{{ . }} {{ end }}</p>{{ end }}{{ end }}
 					</div>
 					{{ end }}
 				</div>
 			</div>
 			{{ end }}
 		</div>
+		{{ end }}
+	</div>
+	{{ end }}
+`
+
+const dashboardPage = `
+	<!DOCTYPE html>
+	<html>
+	<head>
+		<style>
+		body {
+			font-family: Arial, sans-serif;
+			margin: 0;
+			background-color: #f0f0f0;
+			display: flex;
+			flex-direction: column;
+			align-items: center;
+		}
+		.dashboard {
+			max-width: 90%;
+			margin: 10px 20px;
+		}
+		` + DashboardStyle + `
+		</style>
+	</head>
+	<body>
+	<div class="dashboard">
+	` + dashboardSection + `
 	</div>
 	</body>
 	</html>
 	`
 
-	dataMap := map[string]interface{}{
-		"Name":        pipeline.Name,
-		"Status":      pipeline.Status,
-		"StartedTime": pipeline.StartedTime,
-		"Duration":    pipeline.Duration,
-		"StageCount":  pipeline.StageCount,
-		"StepCount":   pipeline.StepCount,
-		"Message":     pipeline.Message,
-		"Stages":      pipeline.Stages,
+// GenerateDashboardHTML generates a standalone HTML page showing the stages
+// and steps of the given pipelines.
+func GenerateDashboardHTML(dashboards ...Dashboard) (string, error) {
+	return renderDashboard("dashboard", dashboardPage, dashboards)
+}
+
+// GenerateDashboardSection generates the dashboard of the given pipelines as
+// an HTML fragment, to be embedded in a page styled with DashboardStyle.
+func GenerateDashboardSection(dashboards ...Dashboard) (template.HTML, error) {
+	section, err := renderDashboard("dashboard-section", dashboardSection, dashboards)
+	return template.HTML(section), err
+}
+
+func renderDashboard(name string, htmlTemplate string, dashboards []Dashboard) (string, error) {
+	fmt.Println("|---------------------------------------------")
+	fmt.Println("| \033[1;36mGenerating dashboard...\033[0m")
+	fmt.Println("|---------------------------------------------")
+
+	prepared := make([]Dashboard, 0, len(dashboards))
+	for _, dashboard := range dashboards {
+		prepared = append(prepared, Dashboard{
+			Title:    dashboard.Title,
+			Pipeline: preparePipeline(dashboard.Pipeline),
+		})
 	}
 
-	tmpl, err := template.New("dashboard").Parse(htmlTemplate)
+	tmpl, err := template.New(name).Parse(htmlTemplate)
 	if err != nil {
 		return "", err
 	}
 
 	var resultHTML strings.Builder
-	err = tmpl.Execute(&resultHTML, dataMap)
+	err = tmpl.Execute(&resultHTML, prepared)
 	if err != nil {
 		return "", err
 	}
 
 	return resultHTML.String(), nil
 }
+
+// preparePipeline returns a copy of the pipeline ready to be displayed:
+// stages and steps sorted by start time, with short timestamps and
+// durations.
+func preparePipeline(pipeline models.Pipeline) models.Pipeline {
+	if startedTime, ok := parseTime(pipeline.StartedTime); ok {
+		pipeline.StartedTime = startedTime.Local().Format("Jan 02 15:04:05 MST")
+	}
+
+	stages := make([]models.Stage, len(pipeline.Stages))
+	copy(stages, pipeline.Stages)
+	pipeline.Stages = stages
+
+	sort.SliceStable(pipeline.Stages, func(i, j int) bool {
+		return startsBefore(pipeline.Stages[i].StartTs, pipeline.Stages[j].StartTs)
+	})
+
+	for i := range pipeline.Stages {
+		stage := &pipeline.Stages[i]
+		stage.StartTs, stage.EndTs, stage.Duration = displayTimes(stage.StartTs, stage.EndTs)
+
+		steps := make([]models.Step, len(stage.Steps))
+		copy(steps, stage.Steps)
+		stage.Steps = steps
+
+		sort.SliceStable(stage.Steps, func(m, n int) bool {
+			if stage.Steps[m].Status == "Skipped" {
+				return false
+			}
+			if stage.Steps[n].Status == "Skipped" {
+				return true
+			}
+			return startsBefore(stage.Steps[m].StartTs, stage.Steps[n].StartTs)
+		})
+
+		for j := range stage.Steps {
+			step := &stage.Steps[j]
+			step.StartTs, step.EndTs, step.Duration = displayTimes(step.StartTs, step.EndTs)
+		}
+	}
+
+	return pipeline
+}
+
+// displayTimes shortens a start and end timestamp and computes the duration
+// between them. Missing timestamps (not started, still running) give empty
+// values.
+func displayTimes(startTs string, endTs string) (string, string, string) {
+	var start, end, duration string
+
+	startTime, started := parseTime(startTs)
+	if started {
+		start = startTime.Local().Format("Jan 02 15:04:05 MST")
+	}
+	endTime, ended := parseTime(endTs)
+	if ended {
+		end = endTime.Local().Format("Jan 02 15:04:05 MST")
+	}
+	if started && ended {
+		duration = formatDuration(endTime.Sub(startTime))
+	}
+
+	return start, end, duration
+}
+
+// startsBefore orders timestamps, with missing ones last.
+func startsBefore(a string, b string) bool {
+	timeA, okA := parseTime(a)
+	timeB, okB := parseTime(b)
+	if okA != okB {
+		return okA
+	}
+	return timeA.Before(timeB)
+}
+
+func parseTime(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	parsed, err := time.Parse(customDateFormat, value)
+	if err != nil {
+		log.Println("Error parsing time: ", err)
+		return time.Time{}, false
+	}
+	return parsed, true
+}
+
+func formatDuration(duration time.Duration) string {
+	if duration < time.Minute {
+		return fmt.Sprintf("%.0f seconds", duration.Seconds())
+	} else if duration < time.Hour {
+		return fmt.Sprintf("%.0f minutes", duration.Minutes())
+	} else if duration < time.Hour*24 {
+		hours := int(duration.Hours())
+		minutes := int(duration.Minutes()) % 60
+		return fmt.Sprintf("%d hours %d minutes", hours, minutes)
+	}
+	days := int(duration.Hours()) / 24
+	hours := int(duration.Hours()) % 24
+	return fmt.Sprintf("%d days %d hours", days, hours)
+}
@@ -28,35 +28,46 @@ type Stage struct {
 
 // Step represents a step in a stage.
 type Step struct {
-	Name     string `json:"name"`
-	Status   string `json:"status"`
-	Message  string `json:"message"`
-	StartTs  string `json:"startTs"`
-	EndTs    string `json:"endTs"`
-	Duration string `json:"duration"`
+	Name        string      `json:"name"`
+	Status      string      `json:"status"`
+	Message     string      `json:"message"`
+	StartTs     string      `json:"startTs"`
+	EndTs       string      `json:"endTs"`
+	Duration    string      `json:"duration"`
+	FailureInfo FailureInfo `json:"failureInfo"`
 }
 
-// steps parsing
-type PayloadSteps struct {
+// ExecutionDetails is the response of the execution details (v2) API.
+type ExecutionDetails struct {
 	Status string `json:"status"`
 	Data   struct {
-		ExecutionGraph struct {
-			NodeMap map[string]Node `json:"nodeMap"`
-		} `json:"executionGraph"`
+		PipelineExecutionSummary Content        `json:"pipelineExecutionSummary"`
+		ExecutionGraph           ExecutionGraph `json:"executionGraph"`
 	} `json:"data"`
 }
 
+// ExecutionGraph holds every node (stages, steps and the sections grouping
+// them) of an execution.
+type ExecutionGraph struct {
+	RootNodeId string          `json:"rootNodeId"`
+	NodeMap    map[string]Node `json:"nodeMap"`
+}
+
 type Node struct {
-	Name        string `json:"name"`
-	Identifier  string `json:"identifier"`
-	StartTs     int64  `json:"startTs"`
-	EndTs       int64  `json:"endTs"`
-	Status      string `json:"status"`
-	StepType    string `json:"stepType"`
-	FailureInfo struct {
-		Message         string   `json:"message"`
-		FailureTypeList []string `json:"failureTypeList"`
-	} `json:"failureInfo"`
+	Uuid        string      `json:"uuid"`
+	Name        string      `json:"name"`
+	Identifier  string      `json:"identifier"`
+	BaseFqn     string      `json:"baseFqn"`
+	StartTs     int64       `json:"startTs"`
+	EndTs       int64       `json:"endTs"`
+	Status      string      `json:"status"`
+	StepType    string      `json:"stepType"`
+	FailureInfo FailureInfo `json:"failureInfo"`
+}
+
+type FailureInfo struct {
+	Message         string   `json:"message"`
+	FailureTypeList []string `json:"failureTypeList"`
 }
 
 // PLUGIN CORE
@@ -122,7 +133,12 @@ type Content struct {
 }
 
 type Data struct {
-	Content []Content `json:"content"`
+	Content    []Content `json:"content"`
+	TotalPages int       `json:"totalPages"`
+	TotalItems int       `json:"totalItems"`
+	PageIndex  int       `json:"pageIndex"`
+	PageSize   int       `json:"pageSize"`
+	Empty      bool      `json:"empty"`
 	// Include other fields if needed
 }
 
@@ -133,20 +149,18 @@ type Response struct {
 }
 
 type NodeInfo struct {
-	NodeType       string     `json:"nodeType"`
-	NodeGroup      string     `json:"nodeGroup"`
-	NodeIdentifier string     `json:"nodeIdentifier"`
-	Name           string     `json:"name"`
-	NodeUuid       string     `json:"nodeUuid"`
-	Status         string     `json:"status"`
-	Module         string     `json:"module"`
-	ModuleInfo     ModuleInfo `json:"moduleInfo"`
-	StartTs        int        `json:"startTs"`
-	EndTs          int        `json:"endTs"`
-	FailureInfo    struct {
-		Message string `json:"message"`
-	} `json:"failureInfo"`
-	EdgeLayoutList EdgeLayout `json:"edgeLayoutList"`
+	NodeType       string      `json:"nodeType"`
+	NodeGroup      string      `json:"nodeGroup"`
+	NodeIdentifier string      `json:"nodeIdentifier"`
+	Name           string      `json:"name"`
+	NodeUuid       string      `json:"nodeUuid"`
+	Status         string      `json:"status"`
+	Module         string      `json:"module"`
+	ModuleInfo     ModuleInfo  `json:"moduleInfo"`
+	StartTs        int         `json:"startTs"`
+	EndTs          int         `json:"endTs"`
+	FailureInfo    FailureInfo `json:"failureInfo"`
+	EdgeLayoutList EdgeLayout  `json:"edgeLayoutList"`
 	// NodeExecutionId string `json:"nodeExecutionId"`
 	// Include other fields if needed
 }
@@ -0,0 +1,130 @@
+// payloads/azure.go
+package payloads
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// azureDevOpsParser handles Azure DevOps (Azure Repos) git.push and
+// git.pullrequest.* service hook events.
+type azureDevOpsParser struct{}
+
+type azureUser struct {
+	DisplayName string `json:"displayName"`
+	UniqueName  string `json:"uniqueName"`
+}
+
+type azureCommit struct {
+	CommitID string `json:"commitId"`
+	Comment  string `json:"comment"`
+	Author   struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+		Date  string `json:"date"`
+	} `json:"author"`
+}
+
+type azurePayload struct {
+	EventType string `json:"eventType"`
+	Resource  struct {
+		Repository struct {
+			Name          string `json:"name"`
+			DefaultBranch string `json:"defaultBranch"`
+			Project       struct {
+				Name       string `json:"name"`
+				Visibility string `json:"visibility"`
+			} `json:"project"`
+		} `json:"repository"`
+		// git.push
+		RefUpdates []struct {
+			Name        string `json:"name"`
+			OldObjectID string `json:"oldObjectId"`
+			NewObjectID string `json:"newObjectId"`
+		} `json:"refUpdates"`
+		PushedBy azureUser     `json:"pushedBy"`
+		Commits  []azureCommit `json:"commits"`
+		// git.pullrequest.*
+		PullRequestID         int       `json:"pullRequestId"`
+		Title                 string    `json:"title"`
+		Status                string    `json:"status"`
+		SourceRefName         string    `json:"sourceRefName"`
+		TargetRefName         string    `json:"targetRefName"`
+		CreatedBy             azureUser `json:"createdBy"`
+		LastMergeSourceCommit struct {
+			CommitID string `json:"commitId"`
+		} `json:"lastMergeSourceCommit"`
+		LastMergeTargetCommit struct {
+			CommitID string `json:"commitId"`
+		} `json:"lastMergeTargetCommit"`
+	} `json:"resource"`
+}
+
+func (azureDevOpsParser) Provider() string {
+	return "azure-devops"
+}
+
+func (azureDevOpsParser) Detect(fields map[string]json.RawMessage) bool {
+	return hasFields(fields, "eventType", "resource") && strings.HasPrefix(stringField(fields, "eventType"), "git.")
+}
+
+func (azureDevOpsParser) Parse(data []byte) (*Event, error) {
+	var payload azurePayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("parsing Azure DevOps payload: %w", err)
+	}
+
+	resource := payload.Resource
+	event := &Event{
+		Provider: "azure-devops",
+		Repository: Repository{
+			Name:          resource.Repository.Name,
+			FullName:      resource.Repository.Project.Name + "/" + resource.Repository.Name,
+			Private:       !strings.EqualFold(resource.Repository.Project.Visibility, "public"),
+			DefaultBranch: branchName(resource.Repository.DefaultBranch),
+		},
+	}
+
+	switch {
+	case payload.EventType == "git.push":
+		if len(resource.RefUpdates) == 0 {
+			return nil, errors.New("Azure DevOps push payload without ref updates")
+		}
+		event.Kind = KindPush
+		event.Actor = User{Name: resource.PushedBy.DisplayName, Username: resource.PushedBy.UniqueName}
+		var changes []RefChange
+		for _, update := range resource.RefUpdates {
+			changes = append(changes, newRefChange(update.Name, update.OldObjectID, update.NewObjectID))
+		}
+		// The commits of a push are not split by ref.
+		for _, commit := range resource.Commits {
+			changes[0].Commits = append(changes[0].Commits, Commit{
+				SHA:       commit.CommitID,
+				Message:   commit.Comment,
+				Author:    User{Name: commit.Author.Name, Email: commit.Author.Email},
+				Timestamp: commit.Author.Date,
+			})
+		}
+		event.setChanges(changes)
+	case strings.HasPrefix(payload.EventType, "git.pullrequest."):
+		event.Kind = KindPullRequest
+		event.Actor = User{Name: resource.CreatedBy.DisplayName, Username: resource.CreatedBy.UniqueName}
+		event.Ref = resource.SourceRefName
+		event.Branch = branchName(resource.SourceRefName)
+		event.NewSHA = resource.LastMergeSourceCommit.CommitID
+		event.PullRequest = &PullRequest{
+			Number:       resource.PullRequestID,
+			Title:        resource.Title,
+			Action:       strings.TrimPrefix(payload.EventType, "git.pullrequest."),
+			SourceBranch: branchName(resource.SourceRefName),
+			TargetBranch: branchName(resource.TargetRefName),
+			TargetSHA:    resource.LastMergeTargetCommit.CommitID,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Azure DevOps event %q", payload.EventType)
+	}
+
+	return event, nil
+}
@@ -0,0 +1,181 @@
+// payloads/bitbucket.go
+package payloads
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// bitbucketCloudParser handles Bitbucket Cloud repo:push and pullrequest:*
+// events.
+type bitbucketCloudParser struct{}
+
+type bitbucketActor struct {
+	DisplayName string `json:"display_name"`
+	Nickname    string `json:"nickname"`
+}
+
+type bitbucketRepository struct {
+	Name       string `json:"name"`
+	FullName   string `json:"full_name"`
+	IsPrivate  bool   `json:"is_private"`
+	MainBranch struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+// bitbucketRef is the old or new state of a ref, null when the ref was
+// created or deleted.
+type bitbucketRef struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Target struct {
+		Hash string `json:"hash"`
+	} `json:"target"`
+}
+
+type bitbucketChange struct {
+	Old     *bitbucketRef `json:"old"`
+	New     *bitbucketRef `json:"new"`
+	Commits []struct {
+		Hash    string `json:"hash"`
+		Message string `json:"message"`
+		Date    string `json:"date"`
+		Author  struct {
+			Raw  string         `json:"raw"`
+			User bitbucketActor `json:"user"`
+		} `json:"author"`
+	} `json:"commits"`
+}
+
+type bitbucketCloudPayload struct {
+	Actor      bitbucketActor      `json:"actor"`
+	Repository bitbucketRepository `json:"repository"`
+	Push       struct {
+		Changes []bitbucketChange `json:"changes"`
+	} `json:"push"`
+	PullRequest *struct {
+		ID     int    `json:"id"`
+		Title  string `json:"title"`
+		State  string `json:"state"`
+		Source struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+			Commit struct {
+				Hash string `json:"hash"`
+			} `json:"commit"`
+		} `json:"source"`
+		Destination struct {
+			Branch struct {
+				Name string `json:"name"`
+			} `json:"branch"`
+			Commit struct {
+				Hash string `json:"hash"`
+			} `json:"commit"`
+		} `json:"destination"`
+	} `json:"pullrequest"`
+}
+
+func (bitbucketCloudParser) Provider() string {
+	return "bitbucket"
+}
+
+func (bitbucketCloudParser) Detect(fields map[string]json.RawMessage) bool {
+	return hasFields(fields, "push", "repository") || hasFields(fields, "pullrequest", "repository")
+}
+
+func (bitbucketCloudParser) Parse(data []byte) (*Event, error) {
+	var payload bitbucketCloudPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("parsing Bitbucket payload: %w", err)
+	}
+
+	event := &Event{
+		Provider: "bitbucket",
+		Repository: Repository{
+			Name:          payload.Repository.Name,
+			FullName:      payload.Repository.FullName,
+			Private:       payload.Repository.IsPrivate,
+			DefaultBranch: payload.Repository.MainBranch.Name,
+		},
+		Actor: User{Name: payload.Actor.DisplayName, Username: payload.Actor.Nickname},
+	}
+
+	if pr := payload.PullRequest; pr != nil {
+		event.Kind = KindPullRequest
+		event.Ref = "refs/heads/" + pr.Source.Branch.Name
+		event.Branch = pr.Source.Branch.Name
+		event.NewSHA = pr.Source.Commit.Hash
+		event.PullRequest = &PullRequest{
+			Number:       pr.ID,
+			Title:        pr.Title,
+			Action:       pr.State,
+			SourceBranch: pr.Source.Branch.Name,
+			TargetBranch: pr.Destination.Branch.Name,
+			TargetSHA:    pr.Destination.Commit.Hash,
+		}
+		return event, nil
+	}
+
+	if len(payload.Push.Changes) == 0 {
+		return nil, errors.New("Bitbucket push payload without changes")
+	}
+
+	event.Kind = KindPush
+	var changes []RefChange
+	for _, change := range payload.Push.Changes {
+		// old is null for created refs, new for deleted ones.
+		ref := change.New
+		var oldSHA, newSHA string
+		if change.Old != nil {
+			oldSHA = change.Old.Target.Hash
+			if ref == nil {
+				ref = change.Old
+			}
+		}
+		if change.New != nil {
+			newSHA = change.New.Target.Hash
+		}
+		if ref == nil {
+			continue
+		}
+
+		fullRef := "refs/heads/" + ref.Name
+		if ref.Type == "tag" {
+			fullRef = "refs/tags/" + ref.Name
+		}
+		refChange := newRefChange(fullRef, oldSHA, newSHA)
+		for _, commit := range change.Commits {
+			author := User{Name: commit.Author.User.DisplayName, Username: commit.Author.User.Nickname}
+			if name, email := splitRawAuthor(commit.Author.Raw); email != "" {
+				author.Email = email
+				if author.Name == "" {
+					author.Name = name
+				}
+			}
+			refChange.Commits = append(refChange.Commits, Commit{
+				SHA:       commit.Hash,
+				Message:   commit.Message,
+				Author:    author,
+				Timestamp: commit.Date,
+			})
+		}
+		changes = append(changes, refChange)
+	}
+	event.setChanges(changes)
+
+	return event, nil
+}
+
+// splitRawAuthor splits a "Name <email>" author.
+func splitRawAuthor(raw string) (string, string) {
+	start := strings.LastIndex(raw, "<")
+	end := strings.LastIndex(raw, ">")
+	if start < 0 || end < start {
+		return strings.TrimSpace(raw), ""
+	}
+	return strings.TrimSpace(raw[:start]), raw[start+1 : end]
+}
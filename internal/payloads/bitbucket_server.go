@@ -0,0 +1,118 @@
+// payloads/bitbucket_server.go
+package payloads
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// bitbucketServerParser handles Bitbucket Server (Data Center)
+// repo:refs_changed and pr:* events.
+type bitbucketServerParser struct{}
+
+type bitbucketServerUser struct {
+	Name         string `json:"name"`
+	DisplayName  string `json:"displayName"`
+	EmailAddress string `json:"emailAddress"`
+}
+
+type bitbucketServerRepository struct {
+	Slug    string `json:"slug"`
+	Name    string `json:"name"`
+	Public  bool   `json:"public"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+}
+
+type bitbucketServerRef struct {
+	ID           string                    `json:"id"`
+	DisplayID    string                    `json:"displayId"`
+	LatestCommit string                    `json:"latestCommit"`
+	Repository   bitbucketServerRepository `json:"repository"`
+}
+
+type bitbucketServerPayload struct {
+	EventKey   string                    `json:"eventKey"`
+	Actor      bitbucketServerUser       `json:"actor"`
+	Repository bitbucketServerRepository `json:"repository"`
+	Changes    []struct {
+		Ref struct {
+			ID        string `json:"id"`
+			DisplayID string `json:"displayId"`
+			Type      string `json:"type"`
+		} `json:"ref"`
+		FromHash string `json:"fromHash"`
+		ToHash   string `json:"toHash"`
+		Type     string `json:"type"`
+	} `json:"changes"`
+	PullRequest *struct {
+		ID      int                `json:"id"`
+		Title   string             `json:"title"`
+		FromRef bitbucketServerRef `json:"fromRef"`
+		ToRef   bitbucketServerRef `json:"toRef"`
+	} `json:"pullRequest"`
+}
+
+func (bitbucketServerParser) Provider() string {
+	return "bitbucket-server"
+}
+
+func (bitbucketServerParser) Detect(fields map[string]json.RawMessage) bool {
+	return hasFields(fields, "eventKey", "actor")
+}
+
+func (bitbucketServerParser) Parse(data []byte) (*Event, error) {
+	var payload bitbucketServerPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("parsing Bitbucket Server payload: %w", err)
+	}
+
+	event := &Event{
+		Provider: "bitbucket-server",
+		Actor:    User{Name: payload.Actor.DisplayName, Username: payload.Actor.Name, Email: payload.Actor.EmailAddress},
+	}
+
+	switch {
+	case strings.HasPrefix(payload.EventKey, "pr:") && payload.PullRequest != nil:
+		pr := payload.PullRequest
+		event.Kind = KindPullRequest
+		event.Repository = bitbucketServerToRepository(pr.ToRef.Repository)
+		event.Ref = pr.FromRef.ID
+		event.Branch = pr.FromRef.DisplayID
+		event.NewSHA = pr.FromRef.LatestCommit
+		event.PullRequest = &PullRequest{
+			Number:       pr.ID,
+			Title:        pr.Title,
+			Action:       strings.TrimPrefix(payload.EventKey, "pr:"),
+			SourceBranch: pr.FromRef.DisplayID,
+			TargetBranch: pr.ToRef.DisplayID,
+			TargetSHA:    pr.ToRef.LatestCommit,
+		}
+	case payload.EventKey == "repo:refs_changed":
+		if len(payload.Changes) == 0 {
+			return nil, errors.New("Bitbucket Server push payload without changes")
+		}
+		event.Kind = KindPush
+		event.Repository = bitbucketServerToRepository(payload.Repository)
+		var changes []RefChange
+		for _, change := range payload.Changes {
+			changes = append(changes, newRefChange(change.Ref.ID, change.FromHash, change.ToHash))
+		}
+		event.setChanges(changes)
+	default:
+		return nil, fmt.Errorf("unsupported Bitbucket Server event %q", payload.EventKey)
+	}
+
+	return event, nil
+}
+
+func bitbucketServerToRepository(repository bitbucketServerRepository) Repository {
+	return Repository{
+		Name:     repository.Slug,
+		FullName: repository.Project.Key + "/" + repository.Slug,
+		Private:  !repository.Public,
+	}
+}
@@ -0,0 +1,263 @@
+// payloads/event.go
+package payloads
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Kinds of events.
+const (
+	KindPush        = "push"
+	KindPullRequest = "pull_request"
+)
+
+// Types of refs.
+const (
+	RefBranch = "branch"
+	RefTag    = "tag"
+)
+
+// Actions of a ref change.
+const (
+	ActionCreated = "created"
+	ActionUpdated = "updated"
+	ActionDeleted = "deleted"
+)
+
+// Event is a webhook event normalised across providers.
+type Event struct {
+	Provider   string
+	Kind       string
+	Repository Repository
+	Actor      User
+	// Ref is the full name of the updated ref, e.g. refs/heads/main. For pull
+	// requests it is the source branch, for pushes the primary change.
+	Ref    string
+	Branch string
+	// OldSHA and NewSHA delimit the change. OldSHA is empty when the branch
+	// was created or is unknown (most pull request events), NewSHA when it
+	// was deleted.
+	OldSHA string
+	NewSHA string
+	// Changes lists every ref updated by a push. A single push may update
+	// several branches and tags.
+	Changes []RefChange
+	// PullRequest is only set for pull request events.
+	PullRequest *PullRequest
+	// Commits lists the commits sent with the event, when the provider
+	// includes them.
+	Commits []Commit
+}
+
+// RefChange is the update of a branch or tag by a push.
+type RefChange struct {
+	// Ref is the full name of the ref, e.g. refs/tags/v1.0.0.
+	Ref string
+	// Name is the short name of the branch or tag.
+	Name string
+	// Type is RefBranch or RefTag.
+	Type string
+	// OldSHA is empty for created refs, NewSHA for deleted ones.
+	OldSHA  string
+	NewSHA  string
+	Commits []Commit
+}
+
+// newRefChange builds a RefChange from a full ref name.
+func newRefChange(ref string, oldSHA string, newSHA string) RefChange {
+	change := RefChange{Ref: ref, Name: ref, Type: RefBranch, OldSHA: sha(oldSHA), NewSHA: sha(newSHA)}
+	switch {
+	case strings.HasPrefix(ref, "refs/tags/"):
+		change.Name = strings.TrimPrefix(ref, "refs/tags/")
+		change.Type = RefTag
+	case strings.HasPrefix(ref, "refs/heads/"):
+		change.Name = strings.TrimPrefix(ref, "refs/heads/")
+	}
+	return change
+}
+
+// Action returns whether the ref was created, updated or deleted.
+func (c RefChange) Action() string {
+	switch {
+	case c.NewSHA == "":
+		return ActionDeleted
+	case c.OldSHA == "":
+		return ActionCreated
+	}
+	return ActionUpdated
+}
+
+// IsRelease reports whether the change pushed a tag, which is treated as a
+// release.
+func (c RefChange) IsRelease() bool {
+	return c.Type == RefTag && c.NewSHA != ""
+}
+
+// setChanges sets the changes of a push event. The first change that does
+// not delete a ref becomes the primary one, described by Ref, Branch, OldSHA
+// and NewSHA.
+func (e *Event) setChanges(changes []RefChange) {
+	e.Changes = changes
+	if len(changes) == 0 {
+		return
+	}
+
+	primary := changes[0]
+	for _, change := range changes {
+		if change.Action() != ActionDeleted {
+			primary = change
+			break
+		}
+	}
+	e.Ref = primary.Ref
+	e.Branch = primary.Name
+	e.OldSHA = primary.OldSHA
+	e.NewSHA = primary.NewSHA
+
+	e.Commits = nil
+	for _, change := range changes {
+		e.Commits = append(e.Commits, change.Commits...)
+	}
+}
+
+type Repository struct {
+	Name          string
+	FullName      string
+	Private       bool
+	DefaultBranch string
+}
+
+type User struct {
+	Name     string
+	Username string
+	Email    string
+}
+
+type PullRequest struct {
+	Number       int
+	Title        string
+	Action       string
+	SourceBranch string
+	TargetBranch string
+	// TargetSHA is the tip of the target branch, when known.
+	TargetSHA string
+}
+
+type Commit struct {
+	SHA       string
+	Message   string
+	Author    User
+	Timestamp string
+}
+
+// Parser normalises the events of one provider.
+type Parser interface {
+	// Provider returns the name of the provider, e.g. "github".
+	Provider() string
+	// Detect reports whether a payload, given by its top-level fields, was
+	// sent by the provider.
+	Detect(fields map[string]json.RawMessage) bool
+	// Parse converts a push or pull request event into an Event.
+	Parse(data []byte) (*Event, error)
+}
+
+// ErrUnknownProvider is returned when no parser handles a payload.
+var ErrUnknownProvider = errors.New("unknown webhook payload provider")
+
+// parsers are tried in order when detecting the provider. Their detection
+// rules do not overlap.
+var parsers = []Parser{
+	gitlabParser{},
+	bitbucketServerParser{},
+	azureDevOpsParser{},
+	githubParser{},
+	bitbucketCloudParser{},
+}
+
+// Register adds a parser, replacing any parser of the same provider.
+func Register(parser Parser) {
+	for i, p := range parsers {
+		if p.Provider() == parser.Provider() {
+			parsers[i] = parser
+			return
+		}
+	}
+	parsers = append(parsers, parser)
+}
+
+// Providers returns the names of the registered providers.
+func Providers() []string {
+	names := make([]string, 0, len(parsers))
+	for _, p := range parsers {
+		names = append(names, p.Provider())
+	}
+	return names
+}
+
+// Parse parses a webhook payload. provider selects the parser, when empty or
+// "auto" the provider is detected from the payload.
+func Parse(data []byte, provider string) (*Event, error) {
+	parser, err := Lookup(data, provider)
+	if err != nil {
+		return nil, err
+	}
+	return parser.Parse(data)
+}
+
+// Lookup returns the parser of the provider, or detects it from the payload
+// when provider is empty or "auto".
+func Lookup(data []byte, provider string) (Parser, error) {
+	if provider == "" || provider == "auto" {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("parsing payload: %w", err)
+		}
+		for _, p := range parsers {
+			if p.Detect(fields) {
+				return p, nil
+			}
+		}
+		return nil, ErrUnknownProvider
+	}
+
+	for _, p := range parsers {
+		if p.Provider() == provider {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q, expected one of %s", ErrUnknownProvider, provider, strings.Join(Providers(), ", "))
+}
+
+// hasFields reports whether all the given top-level fields are present.
+func hasFields(fields map[string]json.RawMessage, names ...string) bool {
+	for _, name := range names {
+		if _, ok := fields[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// stringField returns a top-level string field, or an empty string.
+func stringField(fields map[string]json.RawMessage, name string) string {
+	var value string
+	_ = json.Unmarshal(fields[name], &value)
+	return value
+}
+
+// sha returns hash, or an empty string for the zero hash sent as the old or
+// new hash of created and deleted refs.
+func sha(hash string) string {
+	if strings.Trim(hash, "0") == "" {
+		return ""
+	}
+	return hash
+}
+
+// branchName strips the refs/heads/ prefix of a branch ref.
+func branchName(ref string) string {
+	return strings.TrimPrefix(ref, "refs/heads/")
+}
@@ -0,0 +1,419 @@
+package payloads
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		file string
+		want *Event
+		// actions are the actions of the ref changes, in order.
+		actions []string
+	}{
+		{
+			file: "github-push.json",
+			want: &Event{
+				Provider:   "github",
+				Kind:       KindPush,
+				Repository: Repository{Name: "commit-insights", FullName: "acme/commit-insights", Private: true, DefaultBranch: "main"},
+				Actor:      User{Name: "octocat", Username: "octocat", Email: "12345+octocat@users.noreply.github.com"},
+				Ref:        "refs/heads/main",
+				Branch:     "main",
+				OldSHA:     "9049f1265b7d61be4a8904a9a27120d2064dab3b",
+				NewSHA:     "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+				Changes: []RefChange{{
+					Ref: "refs/heads/main", Name: "main", Type: RefBranch,
+					OldSHA:  "9049f1265b7d61be4a8904a9a27120d2064dab3b",
+					NewSHA:  "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+					Commits: githubPushCommits,
+				}},
+				Commits: githubPushCommits,
+			},
+			actions: []string{ActionUpdated},
+		},
+		{
+			file: "github-push-create.json",
+			want: &Event{
+				Provider:   "github",
+				Kind:       KindPush,
+				Repository: Repository{Name: "commit-insights", FullName: "acme/commit-insights", DefaultBranch: "main"},
+				Actor:      User{Name: "hubot", Username: "hubot", Email: "hubot@example.com"},
+				Ref:        "refs/heads/feature/payloads",
+				Branch:     "feature/payloads",
+				NewSHA:     "5b2e0c4e1d7a3f6b8c9d0e1f2a3b4c5d6e7f8091",
+				Changes: []RefChange{{
+					Ref: "refs/heads/feature/payloads", Name: "feature/payloads", Type: RefBranch,
+					NewSHA: "5b2e0c4e1d7a3f6b8c9d0e1f2a3b4c5d6e7f8091",
+				}},
+			},
+			actions: []string{ActionCreated},
+		},
+		{
+			file: "github-push-delete.json",
+			want: &Event{
+				Provider:   "github",
+				Kind:       KindPush,
+				Repository: Repository{Name: "commit-insights", FullName: "acme/commit-insights", Private: true, DefaultBranch: "main"},
+				Actor:      User{Name: "octocat", Username: "octocat", Email: "12345+octocat@users.noreply.github.com"},
+				Ref:        "refs/tags/v1.2.0",
+				Branch:     "v1.2.0",
+				OldSHA:     "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+				Changes: []RefChange{{
+					Ref: "refs/tags/v1.2.0", Name: "v1.2.0", Type: RefTag,
+					OldSHA: "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+				}},
+			},
+			actions: []string{ActionDeleted},
+		},
+		{
+			file: "github-pull-request.json",
+			want: &Event{
+				Provider:   "github",
+				Kind:       KindPullRequest,
+				Repository: Repository{Name: "commit-insights", FullName: "acme/commit-insights", Private: true, DefaultBranch: "main"},
+				Actor:      User{Username: "octocat"},
+				Ref:        "refs/heads/feature/payloads",
+				Branch:     "feature/payloads",
+				NewSHA:     "5b2e0c4e1d7a3f6b8c9d0e1f2a3b4c5d6e7f8091",
+				PullRequest: &PullRequest{
+					Number: 42, Title: "Add payload parsers", Action: "synchronize",
+					SourceBranch: "feature/payloads", TargetBranch: "main",
+					TargetSHA: "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+				},
+			},
+		},
+		{
+			file: "gitlab-push.json",
+			want: &Event{
+				Provider:   "gitlab",
+				Kind:       KindPush,
+				Repository: Repository{Name: "Diaspora", FullName: "mike/diaspora", Private: true, DefaultBranch: "master"},
+				Actor:      User{Name: "John Smith", Username: "jsmith", Email: "john@example.com"},
+				Ref:        "refs/heads/main",
+				Branch:     "main",
+				OldSHA:     "95790bf891e76fee5e1747ab589903a6a1f80f22",
+				NewSHA:     "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+				Changes: []RefChange{{
+					Ref: "refs/heads/main", Name: "main", Type: RefBranch,
+					OldSHA:  "95790bf891e76fee5e1747ab589903a6a1f80f22",
+					NewSHA:  "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+					Commits: gitlabPushCommits,
+				}},
+				Commits: gitlabPushCommits,
+			},
+			actions: []string{ActionUpdated},
+		},
+		{
+			file: "gitlab-tag-push.json",
+			want: &Event{
+				Provider:   "gitlab",
+				Kind:       KindPush,
+				Repository: Repository{Name: "Example", FullName: "jsmith/example", DefaultBranch: "main"},
+				Actor:      User{Name: "John Smith", Username: "jsmith", Email: "john@example.com"},
+				Ref:        "refs/tags/v1.0.0",
+				Branch:     "v1.0.0",
+				NewSHA:     "82b3d5ae55f7080f1e6022629cdb57bfae7cccc7",
+				Changes: []RefChange{{
+					Ref: "refs/tags/v1.0.0", Name: "v1.0.0", Type: RefTag,
+					NewSHA: "82b3d5ae55f7080f1e6022629cdb57bfae7cccc7",
+				}},
+			},
+			actions: []string{ActionCreated},
+		},
+		{
+			file: "gitlab-merge-request.json",
+			want: &Event{
+				Provider:   "gitlab",
+				Kind:       KindPullRequest,
+				Repository: Repository{Name: "Gitlab Test", FullName: "gitlabhq/gitlab-test", DefaultBranch: "master"},
+				Actor:      User{Name: "Administrator", Username: "root", Email: "admin@example.com"},
+				Ref:        "refs/heads/ms-viewport",
+				Branch:     "ms-viewport",
+				OldSHA:     "a1b2c3d4e5f60718293a4b5c6d7e8f9011223344",
+				NewSHA:     "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+				PullRequest: &PullRequest{
+					Number: 1, Title: "MS-Viewport", Action: "update",
+					SourceBranch: "ms-viewport", TargetBranch: "master",
+				},
+				Commits: []Commit{{
+					SHA:       "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+					Message:   "fixed readme",
+					Author:    User{Name: "GitLab dev user", Email: "gitlabdev@dv6700.(none)"},
+					Timestamp: "2012-01-03T23:36:29+02:00",
+				}},
+			},
+		},
+		{
+			file: "bitbucket-push.json",
+			want: &Event{
+				Provider:   "bitbucket",
+				Kind:       KindPush,
+				Repository: Repository{Name: "analytical-engine", FullName: "lovelace/analytical-engine", Private: true, DefaultBranch: "main"},
+				Actor:      User{Name: "Ada Lovelace", Username: "ada"},
+				Ref:        "refs/tags/v2.0.0",
+				Branch:     "v2.0.0",
+				NewSHA:     "e3c2a9f0b5d64a1e8f7c6b5a4d3e2f1a0b9c8d7e",
+				Changes: []RefChange{
+					{
+						Ref: "refs/tags/v2.0.0", Name: "v2.0.0", Type: RefTag,
+						NewSHA: "e3c2a9f0b5d64a1e8f7c6b5a4d3e2f1a0b9c8d7e",
+					},
+					{
+						Ref: "refs/heads/main", Name: "main", Type: RefBranch,
+						OldSHA:  "7d1e4c9b2a3f5e6d8c0b1a2f3e4d5c6b7a8f9e0d",
+						NewSHA:  "e3c2a9f0b5d64a1e8f7c6b5a4d3e2f1a0b9c8d7e",
+						Commits: bitbucketPushCommits,
+					},
+					{
+						Ref: "refs/heads/feature/payloads", Name: "feature/payloads", Type: RefBranch,
+						OldSHA: "4f6d2b8a1c3e5f7a9b0d2c4e6f8a0b1c3d5e7f9a",
+					},
+				},
+				Commits: bitbucketPushCommits,
+			},
+			actions: []string{ActionCreated, ActionUpdated, ActionDeleted},
+		},
+		{
+			file: "bitbucket-pull-request.json",
+			want: &Event{
+				Provider:   "bitbucket",
+				Kind:       KindPullRequest,
+				Repository: Repository{Name: "analytical-engine", FullName: "lovelace/analytical-engine", DefaultBranch: "main"},
+				Actor:      User{Name: "Charles Babbage", Username: "charles"},
+				Ref:        "refs/heads/feature/payloads",
+				Branch:     "feature/payloads",
+				NewSHA:     "4f6d2b8a1c3e",
+				PullRequest: &PullRequest{
+					Number: 5, Title: "Add the Bitbucket parser", Action: "OPEN",
+					SourceBranch: "feature/payloads", TargetBranch: "main",
+					TargetSHA: "7d1e4c9b2a3f",
+				},
+			},
+		},
+		{
+			file: "bitbucket-server-push.json",
+			want: &Event{
+				Provider:   "bitbucket-server",
+				Kind:       KindPush,
+				Repository: Repository{Name: "repository", FullName: "PROJ/repository", Private: true},
+				Actor:      User{Name: "Administrator", Username: "admin", Email: "admin@example.com"},
+				Ref:        "refs/heads/master",
+				Branch:     "master",
+				OldSHA:     "ecddabb624f6f5ba43816f5926e580a5f680a932",
+				NewSHA:     "178864a7d521b6f5e720b386b2c2b0ef8563e0dc",
+				Changes: []RefChange{
+					{
+						Ref: "refs/heads/old-feature", Name: "old-feature", Type: RefBranch,
+						OldSHA: "ecddabb624f6f5ba43816f5926e580a5f680a932",
+					},
+					{
+						Ref: "refs/heads/master", Name: "master", Type: RefBranch,
+						OldSHA: "ecddabb624f6f5ba43816f5926e580a5f680a932",
+						NewSHA: "178864a7d521b6f5e720b386b2c2b0ef8563e0dc",
+					},
+					{
+						Ref: "refs/tags/v1.0", Name: "v1.0", Type: RefTag,
+						NewSHA: "178864a7d521b6f5e720b386b2c2b0ef8563e0dc",
+					},
+				},
+			},
+			actions: []string{ActionDeleted, ActionUpdated, ActionCreated},
+		},
+		{
+			file: "bitbucket-server-pull-request.json",
+			want: &Event{
+				Provider:   "bitbucket-server",
+				Kind:       KindPullRequest,
+				Repository: Repository{Name: "repository", FullName: "PROJ/repository", Private: true},
+				Actor:      User{Name: "User", Username: "user", Email: "user@example.com"},
+				Ref:        "refs/heads/a-branch",
+				Branch:     "a-branch",
+				NewSHA:     "ef8755f06ee4b28c96a847a95cb8ec8ed6ddd1ca",
+				PullRequest: &PullRequest{
+					Number: 2, Title: "a new file added", Action: "from_ref_updated",
+					SourceBranch: "a-branch", TargetBranch: "master",
+					TargetSHA: "178864a7d521b6f5e720b386b2c2b0ef8563e0dc",
+				},
+			},
+		},
+		{
+			file: "azure-devops-push.json",
+			want: &Event{
+				Provider:   "azure-devops",
+				Kind:       KindPush,
+				Repository: Repository{Name: "Fabrikam-Fiber-Git", FullName: "Fabrikam-Fiber-Git/Fabrikam-Fiber-Git", Private: true, DefaultBranch: "master"},
+				Actor:      User{Name: "Jamal Hartnett", Username: `Windows Live ID\fabrikamfiber4@hotmail.com`},
+				Ref:        "refs/heads/master",
+				Branch:     "master",
+				OldSHA:     "aad331d8d3b131fa9ae03cf5e53965b51942618a",
+				NewSHA:     "33b55f7cb7e7e245323987634f960cf4a6e6bc74",
+				Changes: []RefChange{{
+					Ref: "refs/heads/master", Name: "master", Type: RefBranch,
+					OldSHA:  "aad331d8d3b131fa9ae03cf5e53965b51942618a",
+					NewSHA:  "33b55f7cb7e7e245323987634f960cf4a6e6bc74",
+					Commits: azurePushCommits,
+				}},
+				Commits: azurePushCommits,
+			},
+			actions: []string{ActionUpdated},
+		},
+		{
+			file: "azure-devops-push-create.json",
+			want: &Event{
+				Provider:   "azure-devops",
+				Kind:       KindPush,
+				Repository: Repository{Name: "Fabrikam-Fiber-Git", FullName: "Fabrikam-Fiber-Git/Fabrikam-Fiber-Git", DefaultBranch: "master"},
+				Actor:      User{Name: "Jamal Hartnett", Username: "fabrikamfiber4@hotmail.com"},
+				Ref:        "refs/heads/release/1.0",
+				Branch:     "release/1.0",
+				NewSHA:     "33b55f7cb7e7e245323987634f960cf4a6e6bc74",
+				Changes: []RefChange{{
+					Ref: "refs/heads/release/1.0", Name: "release/1.0", Type: RefBranch,
+					NewSHA: "33b55f7cb7e7e245323987634f960cf4a6e6bc74",
+				}},
+			},
+			actions: []string{ActionCreated},
+		},
+		{
+			file: "azure-devops-pull-request.json",
+			want: &Event{
+				Provider:   "azure-devops",
+				Kind:       KindPullRequest,
+				Repository: Repository{Name: "Fabrikam", FullName: "Fabrikam/Fabrikam", Private: true, DefaultBranch: "master"},
+				Actor:      User{Name: "Jamal Hartnett", Username: "fabrikamfiber4@hotmail.com"},
+				Ref:        "refs/heads/mytopic",
+				Branch:     "mytopic",
+				NewSHA:     "53d54ac915144006c2c9e90d2c7d3880920db49c",
+				PullRequest: &PullRequest{
+					Number: 1, Title: "my first pull request", Action: "updated",
+					SourceBranch: "mytopic", TargetBranch: "master",
+					TargetSHA: "a511f535b1ea495ee0c903badb68fbc83772c882",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parser, err := Lookup(data, "auto")
+			if err != nil {
+				t.Fatalf("detecting the provider: %v", err)
+			}
+			if parser.Provider() != tt.want.Provider {
+				t.Errorf("detected provider %q, want %q", parser.Provider(), tt.want.Provider)
+			}
+
+			for _, provider := range []string{"", tt.want.Provider} {
+				event, err := Parse(data, provider)
+				if err != nil {
+					t.Fatalf("Parse(%q): %v", provider, err)
+				}
+				if !reflect.DeepEqual(event, tt.want) {
+					t.Errorf("Parse(%q) =\n%+v\nwant\n%+v", provider, event, tt.want)
+				}
+			}
+
+			var actions []string
+			for _, change := range tt.want.Changes {
+				actions = append(actions, change.Action())
+			}
+			if !reflect.DeepEqual(actions, tt.actions) {
+				t.Errorf("got actions %v, want %v", actions, tt.actions)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		provider string
+		unknown  bool
+	}{
+		{name: "not JSON", payload: `not json`},
+		{name: "undetected", payload: `{"zen": "Keep it logically awesome.", "hook_id": 1}`, unknown: true},
+		{name: "unknown provider", payload: `{}`, provider: "gitea", unknown: true},
+		{name: "Azure DevOps work item", payload: `{"eventType": "workitem.created", "resource": {}}`, unknown: true},
+		{name: "GitLab issue", payload: `{"object_kind": "issue", "project": {}}`},
+		{name: "Bitbucket push without changes", payload: `{"push": {"changes": []}, "repository": {}}`},
+		{name: "Bitbucket Server push without changes", payload: `{"eventKey": "repo:refs_changed", "actor": {}, "changes": []}`},
+		{name: "Bitbucket Server comment", payload: `{"eventKey": "repo:comment:added", "actor": {}}`},
+		{name: "Azure DevOps push without ref updates", payload: `{"eventType": "git.push", "resource": {"refUpdates": []}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := Parse([]byte(tt.payload), tt.provider)
+			if err == nil {
+				t.Fatalf("Parse succeeded with %+v, want an error", event)
+			}
+			if got := errors.Is(err, ErrUnknownProvider); got != tt.unknown {
+				t.Errorf("got error %q, ErrUnknownProvider %v, want %v", err, got, tt.unknown)
+			}
+		})
+	}
+}
+
+var githubPushCommits = []Commit{
+	{
+		SHA:       "6113728f27ae82c7b1a177c8d03f9e96e0adf246",
+		Message:   "Parse the payload before the repository",
+		Author:    User{Name: "Mona Lisa", Username: "octocat", Email: "12345+octocat@users.noreply.github.com"},
+		Timestamp: "2024-03-04T10:15:00+01:00",
+	},
+	{
+		SHA:       "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+		Message:   "Document the payload settings\n\nList every provider.",
+		Author:    User{Name: "Hubot", Username: "hubot", Email: "hubot@example.com"},
+		Timestamp: "2024-03-04T10:20:00+01:00",
+	},
+}
+
+var gitlabPushCommits = []Commit{
+	{
+		SHA:       "b6568db1bc1dcd7f8b4d5a946b0b91f9dacd7327",
+		Message:   "Update Catalan translation to e38cb41.\n\nSee https://gitlab.com/gitlab-org/gitlab for more information",
+		Author:    User{Name: "Jordi Mallach", Email: "jordi@softcatala.org"},
+		Timestamp: "2011-12-12T14:27:31+02:00",
+	},
+	{
+		SHA:       "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+		Message:   "fixed readme",
+		Author:    User{Name: "GitLab dev user", Email: "gitlabdev@dv6700.(none)"},
+		Timestamp: "2012-01-03T23:36:29+02:00",
+	},
+}
+
+var bitbucketPushCommits = []Commit{
+	{
+		SHA:       "e3c2a9f0b5d64a1e8f7c6b5a4d3e2f1a0b9c8d7e",
+		Message:   "Merged in feature/payloads (pull request #5)\n",
+		Author:    User{Name: "Ada Lovelace", Username: "ada", Email: "ada@example.com"},
+		Timestamp: "2024-03-06T08:30:00+00:00",
+	},
+	{
+		// Authors without a Bitbucket account only have the raw author.
+		SHA:       "4f6d2b8a1c3e5f7a9b0d2c4e6f8a0b1c3d5e7f9a",
+		Message:   "Add the Bitbucket parser\n",
+		Author:    User{Name: "Charles Babbage", Email: "charles@example.com"},
+		Timestamp: "2024-03-05T17:00:00+00:00",
+	},
+}
+
+var azurePushCommits = []Commit{{
+	SHA:       "33b55f7cb7e7e245323987634f960cf4a6e6bc74",
+	Message:   "Fixed bug in web.config file",
+	Author:    User{Name: "Jamal Hartnett", Email: "fabrikamfiber4@hotmail.com"},
+	Timestamp: "2015-02-25T19:01:00Z",
+}}
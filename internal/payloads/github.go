@@ -0,0 +1,113 @@
+// payloads/github.go
+package payloads
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// githubParser handles GitHub push and pull_request events.
+type githubParser struct{}
+
+type githubUser struct {
+	Name     string `json:"name"`
+	Login    string `json:"login"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+type githubRepository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Private       bool   `json:"private"`
+	DefaultBranch string `json:"default_branch"`
+}
+
+type githubPayload struct {
+	Ref        string           `json:"ref"`
+	Before     string           `json:"before"`
+	After      string           `json:"after"`
+	Action     string           `json:"action"`
+	Number     int              `json:"number"`
+	Repository githubRepository `json:"repository"`
+	Pusher     githubUser       `json:"pusher"`
+	Sender     githubUser       `json:"sender"`
+	Commits    []struct {
+		ID        string     `json:"id"`
+		Message   string     `json:"message"`
+		Timestamp string     `json:"timestamp"`
+		Author    githubUser `json:"author"`
+	} `json:"commits"`
+	PullRequest *struct {
+		Number int        `json:"number"`
+		Title  string     `json:"title"`
+		User   githubUser `json:"user"`
+		Head   struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+		Base struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"base"`
+	} `json:"pull_request"`
+}
+
+func (githubParser) Provider() string {
+	return "github"
+}
+
+func (githubParser) Detect(fields map[string]json.RawMessage) bool {
+	return hasFields(fields, "ref", "before", "after", "repository") ||
+		hasFields(fields, "pull_request", "action", "repository")
+}
+
+func (githubParser) Parse(data []byte) (*Event, error) {
+	var payload githubPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("parsing GitHub payload: %w", err)
+	}
+
+	event := &Event{
+		Provider: "github",
+		Repository: Repository{
+			Name:          payload.Repository.Name,
+			FullName:      payload.Repository.FullName,
+			Private:       payload.Repository.Private,
+			DefaultBranch: payload.Repository.DefaultBranch,
+		},
+		Actor: User{Name: payload.Pusher.Name, Username: payload.Sender.Login, Email: payload.Pusher.Email},
+	}
+
+	if pr := payload.PullRequest; pr != nil {
+		event.Kind = KindPullRequest
+		event.Ref = "refs/heads/" + pr.Head.Ref
+		event.Branch = pr.Head.Ref
+		event.NewSHA = pr.Head.SHA
+		if event.Actor.Username == "" {
+			event.Actor.Username = pr.User.Login
+		}
+		event.PullRequest = &PullRequest{
+			Number:       pr.Number,
+			Title:        pr.Title,
+			Action:       payload.Action,
+			SourceBranch: pr.Head.Ref,
+			TargetBranch: pr.Base.Ref,
+			TargetSHA:    pr.Base.SHA,
+		}
+		return event, nil
+	}
+
+	event.Kind = KindPush
+	change := newRefChange(payload.Ref, payload.Before, payload.After)
+	for _, commit := range payload.Commits {
+		change.Commits = append(change.Commits, Commit{
+			SHA:       commit.ID,
+			Message:   commit.Message,
+			Author:    User{Name: commit.Author.Name, Username: commit.Author.Username, Email: commit.Author.Email},
+			Timestamp: commit.Timestamp,
+		})
+	}
+	event.setChanges([]RefChange{change})
+	return event, nil
+}
@@ -0,0 +1,124 @@
+// payloads/gitlab.go
+package payloads
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// gitlabParser handles GitLab push, tag push and merge request events.
+type gitlabParser struct{}
+
+// gitlabPrivate is the visibility level of private projects.
+const gitlabPrivate = 0
+
+type gitlabProject struct {
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	DefaultBranch     string `json:"default_branch"`
+	VisibilityLevel   int    `json:"visibility_level"`
+}
+
+type gitlabCommit struct {
+	ID        string `json:"id"`
+	Message   string `json:"message"`
+	Timestamp string `json:"timestamp"`
+	Author    struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	} `json:"author"`
+}
+
+type gitlabPayload struct {
+	ObjectKind   string         `json:"object_kind"`
+	Ref          string         `json:"ref"`
+	Before       string         `json:"before"`
+	After        string         `json:"after"`
+	UserName     string         `json:"user_name"`
+	UserUsername string         `json:"user_username"`
+	UserEmail    string         `json:"user_email"`
+	Project      gitlabProject  `json:"project"`
+	Commits      []gitlabCommit `json:"commits"`
+	User         struct {
+		Name     string `json:"name"`
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	} `json:"user"`
+	ObjectAttributes struct {
+		IID          int          `json:"iid"`
+		Title        string       `json:"title"`
+		Action       string       `json:"action"`
+		SourceBranch string       `json:"source_branch"`
+		TargetBranch string       `json:"target_branch"`
+		OldRev       string       `json:"oldrev"`
+		LastCommit   gitlabCommit `json:"last_commit"`
+	} `json:"object_attributes"`
+}
+
+func (gitlabParser) Provider() string {
+	return "gitlab"
+}
+
+func (gitlabParser) Detect(fields map[string]json.RawMessage) bool {
+	return hasFields(fields, "object_kind", "project")
+}
+
+func (gitlabParser) Parse(data []byte) (*Event, error) {
+	var payload gitlabPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("parsing GitLab payload: %w", err)
+	}
+
+	event := &Event{
+		Provider: "gitlab",
+		Repository: Repository{
+			Name:          payload.Project.Name,
+			FullName:      payload.Project.PathWithNamespace,
+			Private:       payload.Project.VisibilityLevel == gitlabPrivate,
+			DefaultBranch: payload.Project.DefaultBranch,
+		},
+	}
+
+	switch payload.ObjectKind {
+	case "push", "tag_push":
+		event.Kind = KindPush
+		event.Actor = User{Name: payload.UserName, Username: payload.UserUsername, Email: payload.UserEmail}
+		change := newRefChange(payload.Ref, payload.Before, payload.After)
+		for _, commit := range payload.Commits {
+			change.Commits = append(change.Commits, gitlabToCommit(commit))
+		}
+		event.setChanges([]RefChange{change})
+	case "merge_request":
+		attributes := payload.ObjectAttributes
+		event.Kind = KindPullRequest
+		event.Actor = User{Name: payload.User.Name, Username: payload.User.Username, Email: payload.User.Email}
+		event.Ref = "refs/heads/" + attributes.SourceBranch
+		event.Branch = attributes.SourceBranch
+		// oldrev is only sent when the merge request got new commits.
+		event.OldSHA = sha(attributes.OldRev)
+		event.NewSHA = attributes.LastCommit.ID
+		event.PullRequest = &PullRequest{
+			Number:       attributes.IID,
+			Title:        attributes.Title,
+			Action:       attributes.Action,
+			SourceBranch: attributes.SourceBranch,
+			TargetBranch: attributes.TargetBranch,
+		}
+		if attributes.LastCommit.ID != "" {
+			event.Commits = []Commit{gitlabToCommit(attributes.LastCommit)}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported GitLab event %q", payload.ObjectKind)
+	}
+
+	return event, nil
+}
+
+func gitlabToCommit(commit gitlabCommit) Commit {
+	return Commit{
+		SHA:       commit.ID,
+		Message:   commit.Message,
+		Author:    User{Name: commit.Author.Name, Email: commit.Author.Email},
+		Timestamp: commit.Timestamp,
+	}
+}
@@ -0,0 +1,75 @@
+// payloads/signature.go
+package payloads
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// ErrInvalidSignature is returned when a payload does not match its
+// signature.
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Verify checks that a payload was sent by the provider, using the secret
+// shared with it and the signature sent along the payload:
+//   - github, bitbucket and bitbucket-server sign the payload with an HMAC,
+//     sent as sha256=<hex> (X-Hub-Signature-256 or X-Hub-Signature), or
+//     sha1=<hex> by older GitHub webhooks
+//   - gitlab sends the secret itself as the X-Gitlab-Token header
+//
+// The payload must be the raw request body, as signed by the provider.
+func Verify(provider string, payload []byte, signature string, secret string) error {
+	signature = strings.TrimSpace(signature)
+	if signature == "" {
+		return fmt.Errorf("%w: no signature", ErrInvalidSignature)
+	}
+
+	switch provider {
+	case "gitlab":
+		if subtle.ConstantTimeCompare([]byte(signature), []byte(secret)) != 1 {
+			return fmt.Errorf("%w: token mismatch", ErrInvalidSignature)
+		}
+		return nil
+	case "github", "bitbucket", "bitbucket-server":
+		return verifyHMAC(payload, signature, secret)
+	default:
+		return fmt.Errorf("webhook signatures are not supported for %s payloads", provider)
+	}
+}
+
+// verifyHMAC checks an <algorithm>=<hex digest> signature.
+func verifyHMAC(payload []byte, signature string, secret string) error {
+	algorithm, digest, found := strings.Cut(signature, "=")
+	if !found {
+		return fmt.Errorf("%w: expected <algorithm>=<digest>", ErrInvalidSignature)
+	}
+
+	var newHash func() hash.Hash
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		newHash = sha256.New
+	case "sha1":
+		newHash = sha1.New
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, algorithm)
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return fmt.Errorf("%w: malformed digest", ErrInvalidSignature)
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(mac.Sum(nil), expected) {
+		return fmt.Errorf("%w: digest mismatch", ErrInvalidSignature)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package payloads
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	// The example of GitHub's webhook documentation.
+	const githubSecret = "It's a Secret to Everybody"
+	const githubPayload = "Hello, World!"
+	const githubSignature = "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+
+	push, err := os.ReadFile(filepath.Join("testdata", "bitbucket-push.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	githubPush, err := os.ReadFile(filepath.Join("testdata", "github-push.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		provider  string
+		payload   string
+		signature string
+		secret    string
+		// err is "" when the payload is valid, "invalid" for
+		// ErrInvalidSignature and "other" for any other error.
+		err string
+	}{
+		{name: "GitHub sha256", provider: "github", payload: githubPayload, signature: githubSignature, secret: githubSecret},
+		{name: "GitHub sha256 with spaces", provider: "github", payload: githubPayload, signature: " " + githubSignature + "\n", secret: githubSecret},
+		{name: "GitHub sha1", provider: "github", payload: string(githubPush), signature: "sha1=29f919ef8781f526fc4d9cff1c3597e9590dc6d5", secret: githubSecret},
+		{name: "GitHub tampered payload", provider: "github", payload: "Hello, World?", signature: githubSignature, secret: githubSecret, err: "invalid"},
+		{name: "GitHub wrong secret", provider: "github", payload: githubPayload, signature: githubSignature, secret: "It's a Secret to Nobody", err: "invalid"},
+		{name: "Bitbucket sha256", provider: "bitbucket", payload: string(push), signature: "sha256=9f1cef044a3bfec44c7729fed401e0fbadf275265172d2771d93409cd7430872", secret: "bitbucket-secret"},
+		{name: "Bitbucket sha1", provider: "bitbucket", payload: string(push), signature: "sha1=db0a59be85313b8dee58bee63bd56e4963985e82", secret: "bitbucket-secret"},
+		{name: "Bitbucket Server uppercase digest", provider: "bitbucket-server", payload: string(push), signature: "sha256=9F1CEF044A3BFEC44C7729FED401E0FBADF275265172D2771D93409CD7430872", secret: "bitbucket-secret"},
+		{name: "Bitbucket tampered payload", provider: "bitbucket", payload: string(push) + " ", signature: "sha256=9f1cef044a3bfec44c7729fed401e0fbadf275265172d2771d93409cd7430872", secret: "bitbucket-secret", err: "invalid"},
+		{name: "GitLab token", provider: "gitlab", payload: "{}", signature: "gitlab-token", secret: "gitlab-token"},
+		{name: "GitLab wrong token", provider: "gitlab", payload: "{}", signature: "gitlab-token-2", secret: "gitlab-token", err: "invalid"},
+		{name: "missing signature", provider: "github", payload: githubPayload, signature: "", secret: githubSecret, err: "invalid"},
+		{name: "blank signature", provider: "gitlab", payload: "{}", signature: "  ", secret: "gitlab-token", err: "invalid"},
+		{name: "malformed digest", provider: "github", payload: githubPayload, signature: "sha256=not-hex", secret: githubSecret, err: "invalid"},
+		{name: "odd length digest", provider: "github", payload: githubPayload, signature: githubSignature[:len(githubSignature)-1], secret: githubSecret, err: "invalid"},
+		{name: "missing algorithm", provider: "github", payload: githubPayload, signature: "757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17", secret: githubSecret, err: "invalid"},
+		{name: "unsupported algorithm", provider: "github", payload: githubPayload, signature: "md5=65a8e27d8879283831b664bd8b7f0ad4", secret: githubSecret, err: "invalid"},
+		{name: "unsigned provider", provider: "azure-devops", payload: "{}", signature: "sha256=00", secret: "secret", err: "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.provider, []byte(tt.payload), tt.signature, tt.secret)
+			var got string
+			switch {
+			case err == nil:
+			case errors.Is(err, ErrInvalidSignature):
+				got = "invalid"
+			default:
+				got = "other"
+			}
+			if got != tt.err {
+				t.Errorf("Verify() = %v, want %q error", err, tt.err)
+			}
+		})
+	}
+}
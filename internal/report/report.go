@@ -0,0 +1,146 @@
+// report/report.go
+package report
+
+import (
+	"html/template"
+	"time"
+)
+
+// Report is the result of a run: the commits of the range with the people,
+// files and pipeline around them. It is the data every output format is
+// rendered from.
+type Report struct {
+	Repository Repository
+	// Baseline is the pipeline execution the range starts from, nil when none
+	// was found.
+	Baseline *PipelineRef
+	// ExecutionURL links to the current pipeline execution.
+	ExecutionURL string
+	BuildCreated time.Time
+	Range        Range
+	// Committers are the people credited on the commits, as author or
+	// co-author, once each, sorted by name.
+	Committers []Person
+	// Commits are the commits of the range, newest first.
+	Commits   []CommitEntry
+	MergedPRs []MergedPR
+	// Dashboard is the pipeline dashboard, generated by htmlgenerator.
+	Dashboard template.HTML
+}
+
+type Repository struct {
+	Name      string
+	Branch    string
+	BuildType string
+}
+
+// PipelineRef is a pipeline execution, as shown in the report.
+type PipelineRef struct {
+	Name        string
+	Status      string
+	StartedTime string
+	Duration    string
+}
+
+// Range is the commit range of the report. Refs lists the refs updated by a
+// push payload, each with its own range; the range of the report is then
+// that of the first ref logged.
+type Range struct {
+	LogRange
+	Refs []RefUpdate
+}
+
+// LogRange is a range as logged, after the range strategy was resolved.
+type LogRange struct {
+	// Strategy is the resolved strategy, e.g. merge-base for a pull request
+	// built with auto.
+	Strategy string
+	Old      string
+	New      string
+	// ExcludeOld is set when Old itself is not part of the range (old..new
+	// rather than old^..new).
+	ExcludeOld bool
+}
+
+type RefUpdate struct {
+	Ref  string
+	Name string
+	// Type is branch or tag.
+	Type string
+	// Action is created, updated or deleted.
+	Action  string
+	OldSHA  string
+	NewSHA  string
+	Release bool
+	// Range is the range logged for the ref, nil when it was deleted.
+	Range *LogRange
+}
+
+// Person is a canonical identity, after the mailmap was applied.
+type Person struct {
+	Name     string
+	Email    string
+	Username string
+}
+
+// DisplayName returns the name of the person, falling back to the email.
+func (p Person) DisplayName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.Email
+}
+
+type CommitEntry struct {
+	Hash        string
+	Title       string
+	Body        string
+	Author      Person
+	Committer   Person
+	Time        time.Time
+	Parents     []string
+	Merge       bool
+	CoAuthors   []Person
+	ReviewedBy  []Person
+	SignedOffBy []Person
+	Additions   int
+	Deletions   int
+	Changes     []FileChange
+}
+
+// ShortHash abbreviates the commit hash the way git does by default.
+func (c CommitEntry) ShortHash() string {
+	return ShortHash(c.Hash)
+}
+
+// Credited returns the author followed by the co-authors.
+func (c CommitEntry) Credited() []Person {
+	return append([]Person{c.Author}, c.CoAuthors...)
+}
+
+// FileChange is the change of a file in a commit. Status is the git status
+// letter: A, M, D, R, C or T.
+type FileChange struct {
+	Path string
+	// OldPath is the source path of a renamed or copied file.
+	OldPath    string
+	Status     string
+	Similarity int
+	Additions  int
+	Deletions  int
+	Binary     bool
+}
+
+// MergedPR is a merge commit together with the commits it brought in.
+type MergedPR struct {
+	Merge   CommitEntry
+	Commits []CommitEntry
+}
+
+// ShortHash abbreviates a commit hash the way git does by default.
+func ShortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,169 @@
+// report/stats.go
+package report
+
+import (
+	"sort"
+	"strings"
+)
+
+// Stats are the totals of a report.
+type Stats struct {
+	Commits      int
+	MergeCommits int
+	MergedPRs    int
+	Authors      int
+	FilesChanged int
+	Additions    int
+	Deletions    int
+	BinaryFiles  int
+}
+
+// Author is a person credited on commits, with the lines of those commits.
+type Author struct {
+	Person
+	Commits   int
+	Additions int
+	Deletions int
+}
+
+// FileSummary sums up the changes of a file over the whole range. Status is
+// the status of its latest change.
+type FileSummary struct {
+	Path      string
+	Status    string
+	Additions int
+	Deletions int
+	Binary    bool
+	// Commits are the hashes of the commits that changed the file, newest
+	// first.
+	Commits []string
+}
+
+// Stats computes the totals of the report.
+func (r *Report) Stats() Stats {
+	stats := Stats{
+		MergedPRs: len(r.MergedPRs),
+		Authors:   len(r.Authors()),
+	}
+	for _, commit := range r.Commits {
+		stats.Commits++
+		if commit.Merge {
+			stats.MergeCommits++
+		}
+		stats.Additions += commit.Additions
+		stats.Deletions += commit.Deletions
+	}
+	for _, file := range r.Files() {
+		stats.FilesChanged++
+		if file.Binary {
+			stats.BinaryFiles++
+		}
+	}
+	return stats
+}
+
+// Authors returns the people credited on the commits, as author or
+// co-author, most commits first. Like the committers, two identities are the
+// same person when they share an email address or a name (case insensitive),
+// and a person credited twice on a commit counts once.
+func (r *Report) Authors() []Author {
+	var authors []Author
+	byEmail := make(map[string]int)
+	byName := make(map[string]int)
+	for _, commit := range r.Commits {
+		credited := make(map[int]bool)
+		for _, person := range commit.Credited() {
+			emailKey := strings.ToLower(person.Email)
+			nameKey := strings.ToLower(person.Name)
+			if emailKey == "" && nameKey == "" {
+				continue
+			}
+
+			var i int
+			var found bool
+			if emailKey != "" {
+				i, found = byEmail[emailKey]
+			}
+			if !found && nameKey != "" {
+				i, found = byName[nameKey]
+			}
+			if !found {
+				i = len(authors)
+				authors = append(authors, Author{Person: Person{Name: person.Name, Email: person.Email}})
+			}
+			if emailKey != "" {
+				byEmail[emailKey] = i
+			}
+			if nameKey != "" {
+				byName[nameKey] = i
+			}
+
+			author := &authors[i]
+			if author.Name == "" {
+				author.Name = person.Name
+			}
+			if author.Email == "" {
+				author.Email = person.Email
+			}
+			if author.Username == "" {
+				author.Username = person.Username
+			}
+			if credited[i] {
+				continue
+			}
+			credited[i] = true
+			author.Commits++
+			author.Additions += commit.Additions
+			author.Deletions += commit.Deletions
+		}
+	}
+
+	sort.SliceStable(authors, func(i, j int) bool {
+		if authors[i].Commits != authors[j].Commits {
+			return authors[i].Commits > authors[j].Commits
+		}
+		return strings.ToLower(authors[i].Name) < strings.ToLower(authors[j].Name)
+	})
+	return authors
+}
+
+// Files returns the files changed over the range, sorted by path.
+func (r *Report) Files() []FileSummary {
+	var files []FileSummary
+	index := make(map[string]int)
+	for _, commit := range r.Commits {
+		for _, change := range commit.Changes {
+			i, ok := index[change.Path]
+			if !ok {
+				i = len(files)
+				index[change.Path] = i
+				// Commits are listed newest first, so the first change is
+				// the latest.
+				files = append(files, FileSummary{Path: change.Path, Status: change.Status})
+			}
+			file := &files[i]
+			if n := len(file.Commits); n == 0 || file.Commits[n-1] != commit.Hash {
+				file.Commits = append(file.Commits, commit.Hash)
+			}
+			file.Additions += change.Additions
+			file.Deletions += change.Deletions
+			file.Binary = file.Binary || change.Binary
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// Releases returns the names of the tags released by the push.
+func (r *Report) Releases() []string {
+	var releases []string
+	for _, ref := range r.Range.Refs {
+		if ref.Release {
+			releases = append(releases, ref.Name)
+		}
+	}
+	return releases
+}
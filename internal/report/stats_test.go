@@ -0,0 +1,49 @@
+package report
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuthors(t *testing.T) {
+	ada := Person{Name: "Ada Lovelace", Email: "ada@example.com", Username: "ada"}
+	// Same name, another email: e.g. a co-author trailer with a personal
+	// address not covered by the mailmap.
+	adaHome := Person{Name: "ada lovelace", Email: "ada@home.example.com"}
+	// Same email, no name.
+	adaNoName := Person{Email: "ADA@example.com"}
+	grace := Person{Name: "Grace Hopper", Email: "grace@example.com"}
+	// Neither a name nor an email, not credited.
+	nobody := Person{Username: "ghost"}
+
+	r := &Report{Commits: []CommitEntry{
+		{Hash: "a", Author: ada, Additions: 1},
+		{Hash: "b", Author: adaHome, Additions: 2, Deletions: 1},
+		{Hash: "c", Author: grace, CoAuthors: []Person{adaNoName, nobody}, Additions: 4},
+		{Hash: "d", Author: ada, CoAuthors: []Person{adaHome}, Deletions: 8},
+	}}
+
+	want := []Author{
+		{Person: ada, Commits: 4, Additions: 7, Deletions: 9},
+		{Person: grace, Commits: 1, Additions: 4},
+	}
+	if got := r.Authors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got authors %+v, want %+v", got, want)
+	}
+	if got := r.Stats().Authors; got != len(want) {
+		t.Errorf("got %d authors in the stats, want %d", got, len(want))
+	}
+}
+
+func TestAuthorsFillsIdentity(t *testing.T) {
+	r := &Report{Commits: []CommitEntry{
+		{Hash: "a", Author: Person{Email: "grace@example.com"}},
+		{Hash: "b", Author: Person{Name: "Grace Hopper", Email: "grace@example.com", Username: "grace"}},
+		{Hash: "c", Author: Person{Name: "Grace Hopper"}},
+	}}
+
+	want := []Author{{Person: Person{Name: "Grace Hopper", Email: "grace@example.com", Username: "grace"}, Commits: 3}}
+	if got := r.Authors(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got authors %+v, want %+v", got, want)
+	}
+}
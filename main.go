@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/urfave/cli"
 )
@@ -36,9 +37,9 @@ func main() {
 			Usage:  "FAST_CISTO_SonarQube_Quality_Gate_Plugin",
 			EnvVar: "HARNESS_PIPELINE_ID, PLUGIN_PIPELINE_ID",
 		},
-		cli.StringFlag{
+		cli.StringSliceFlag{
 			Name:   "stageID",
-			Usage:  "Build_Golang",
+			Usage:  "Comma-separated list of stage identifiers that must have reached one of the statuses of statusList in the baseline execution. E.g: Build_Golang",
 			EnvVar: "HARNESS_STAGE_ID, PLUGIN_STAGE_ID",
 		},
 		cli.StringSliceFlag{
@@ -47,6 +48,11 @@ func main() {
 			Value:  &cli.StringSlice{"Success"},
 			EnvVar: "PLUGIN_STATUS_LIST",
 		},
+		cli.StringFlag{
+			Name:   "execution_id",
+			Usage:  "Plan execution ID of the running execution, never used as baseline",
+			EnvVar: "HARNESS_EXECUTION_ID, PLUGIN_EXECUTION_ID",
+		},
 		cli.StringFlag{
 			Name:   "repoName",
 			Usage:  "sonarqube-scanner",
@@ -83,30 +89,169 @@ func main() {
 			Usage:  "Provide a custom Harness execution URL, or it gonna take the current pipeline execution URL (Optional)",
 			EnvVar: "CI_BUILD_LINK, PLUGIN_HARNESS_PIPE_EXECUTION_URL",
 		},
+		cli.StringFlag{
+			Name:   "harness_url",
+			Usage:  "Harness API base URL, for self-managed platforms or regional clusters",
+			Value:  DefaultHarnessURL,
+			EnvVar: "PLUGIN_HARNESS_URL",
+		},
+		cli.StringFlag{
+			Name:   "harness_ca_bundle",
+			Usage:  "Path to a PEM bundle of extra certificate authorities to trust when calling Harness",
+			EnvVar: "PLUGIN_HARNESS_CA_BUNDLE",
+		},
+		cli.StringFlag{
+			Name:   "harness_proxy",
+			Usage:  "Proxy URL for Harness API calls (defaults to the HTTP_PROXY/HTTPS_PROXY environment variables)",
+			EnvVar: "PLUGIN_HARNESS_PROXY",
+		},
+		cli.DurationFlag{
+			Name:   "harness_timeout",
+			Usage:  "Timeout of each Harness API request",
+			Value:  30 * time.Second,
+			EnvVar: "PLUGIN_HARNESS_TIMEOUT",
+		},
+		cli.IntFlag{
+			Name:   "harness_max_retries",
+			Usage:  "Number of retries of Harness API calls failing with a network error, 429 or 5xx",
+			Value:  3,
+			EnvVar: "PLUGIN_HARNESS_MAX_RETRIES",
+		},
+		cli.IntFlag{
+			Name:   "lookback_limit",
+			Usage:  "Maximum number of past executions to examine when looking for a baseline execution with commits",
+			Value:  20,
+			EnvVar: "PLUGIN_LOOKBACK_LIMIT",
+		},
+		cli.StringFlag{
+			Name:   "git_backend",
+			Usage:  "How to read the git history: auto, exec (git binary) or go-git (pure Go)",
+			Value:  BackendAuto,
+			EnvVar: "PLUGIN_GIT_BACKEND",
+		},
+		cli.IntFlag{
+			Name:   "max_fetch_depth",
+			Usage:  "Maximum number of commits to fetch when deepening a shallow clone to reach the base commit (0 disables it)",
+			Value:  1000,
+			EnvVar: "PLUGIN_MAX_FETCH_DEPTH",
+		},
+		cli.StringFlag{
+			Name:   "range_strategy",
+			Usage:  "Which commits to report: auto, range (baseline..current), merge-base (pull request commits only) or first-parent",
+			Value:  RangeAuto,
+			EnvVar: "PLUGIN_RANGE_STRATEGY",
+		},
+		cli.StringFlag{
+			Name:   "target_branch",
+			Usage:  "Target branch of the pull request, used by the merge-base range strategy",
+			EnvVar: "DRONE_TARGET_BRANCH, PLUGIN_TARGET_BRANCH",
+		},
+		cli.BoolFlag{
+			Name:   "merge_diffs",
+			Usage:  "Report the file changes of merge commits against their first parent",
+			EnvVar: "PLUGIN_MERGE_DIFFS",
+		},
+		cli.StringFlag{
+			Name:   "alias_file",
+			Usage:  "Path to a file in .mailmap format mapping commit identities to people, applied on top of the repository's .mailmap",
+			EnvVar: "PLUGIN_ALIAS_FILE",
+		},
+		cli.StringFlag{
+			Name:   "payload_provider",
+			Usage:  "Provider of the webhook payload in payload mode: auto, github, gitlab, bitbucket, bitbucket-server or azure-devops",
+			Value:  "auto",
+			EnvVar: "PLUGIN_PAYLOAD_PROVIDER",
+		},
+		cli.StringFlag{
+			Name:   "json_file_name",
+			Usage:  "Path of the webhook payload file in payload mode",
+			EnvVar: "PLUGIN_JSON_FILE_NAME",
+		},
+		cli.StringFlag{
+			Name:   "json_content",
+			Usage:  "Webhook payload in payload mode",
+			EnvVar: "PLUGIN_JSON_CONTENT",
+		},
+		cli.BoolFlag{
+			Name:   "json_stdin",
+			Usage:  "Read the webhook payload from the standard input in payload mode",
+			EnvVar: "PLUGIN_JSON_STDIN",
+		},
+		cli.StringFlag{
+			Name:   "json_env",
+			Usage:  "Name of an environment variable holding the webhook payload in payload mode, e.g. TRIGGER_PAYLOAD",
+			EnvVar: "PLUGIN_JSON_ENV",
+		},
+		cli.StringSliceFlag{
+			Name:   "format",
+			Usage:  "Report format to write, optionally with its path, e.g. markdown=summary.md. Repeatable: html, json, markdown, csv or text (default: html and json)",
+			EnvVar: "PLUGIN_FORMAT",
+		},
+		cli.StringFlag{
+			Name:   "template",
+			Usage:  "Report template file, or directory with a report file and its partials. .html templates replace the HTML report, other files the text report",
+			EnvVar: "PLUGIN_TEMPLATE",
+		},
+		cli.IntFlag{
+			Name:   "markdown_max_size",
+			Usage:  "Maximum size in bytes of the Markdown report, commits and files that do not fit are left out (0 disables it)",
+			Value:  DefaultMarkdownMaxSize,
+			EnvVar: "PLUGIN_MARKDOWN_MAX_SIZE",
+		},
+		cli.StringFlag{
+			Name:   "webhook_secret",
+			Usage:  "Secret shared with the Git provider. When set, payloads without a valid webhook_signature are refused",
+			EnvVar: "PLUGIN_WEBHOOK_SECRET",
+		},
+		cli.StringFlag{
+			Name:   "webhook_signature",
+			Usage:  "Signature header of the webhook: X-Hub-Signature-256 (GitHub), X-Gitlab-Token (GitLab) or X-Hub-Signature (Bitbucket)",
+			EnvVar: "PLUGIN_WEBHOOK_SIGNATURE",
+		},
 	}
 	app.Run(os.Args)
 }
 
 func run(c *cli.Context) {
-	if c.String("json_file_name") != "" && c.String("json_content") != "" {
-		fmt.Println("Error: Please specify either json_file_name or json_content, but not both.")
-		os.Exit(1)
-	}
-
 	config := Config{
-		AccID:            c.String("acc_id"),
-		OrgID:            c.String("orgID"),
-		ProjectID:        c.String("projectID"),
-		PipelineID:       c.String("pipelineID"),
-		StageID:          c.String("stageID"),
-		StatusList:       c.StringSlice("statusList"),
-		RepoName:         c.String("repoName"),
-		Branch:           c.String("branch"),
-		BuildType:        c.String("buildType"),
-		IngestionType:    c.String("ingestionType"),
-		CommitID:         c.String("commit_id"),
-		HarnessSecret:    c.String("harness_secret"),
-		PipeExecutionURL: c.String("harness_pipe_execution_url"),
+		AccID:             c.String("acc_id"),
+		OrgID:             c.String("orgID"),
+		ProjectID:         c.String("projectID"),
+		PipelineID:        c.String("pipelineID"),
+		StageIDs:          c.StringSlice("stageID"),
+		ExecutionID:       c.String("execution_id"),
+		StatusList:        c.StringSlice("statusList"),
+		RepoName:          c.String("repoName"),
+		Branch:            c.String("branch"),
+		BuildType:         c.String("buildType"),
+		IngestionType:     c.String("ingestionType"),
+		CommitID:          c.String("commit_id"),
+		HarnessSecret:     c.String("harness_secret"),
+		PipeExecutionURL:  c.String("harness_pipe_execution_url"),
+		HarnessURL:        c.String("harness_url"),
+		HarnessCABundle:   c.String("harness_ca_bundle"),
+		HarnessProxy:      c.String("harness_proxy"),
+		HarnessTimeout:    c.Duration("harness_timeout"),
+		HarnessMaxRetries: c.Int("harness_max_retries"),
+		LookbackLimit:     c.Int("lookback_limit"),
+		GitBackend:        c.String("git_backend"),
+		MaxFetchDepth:     c.Int("max_fetch_depth"),
+		RangeStrategy:     c.String("range_strategy"),
+		TargetBranch:      c.String("target_branch"),
+		MergeDiffs:        c.Bool("merge_diffs"),
+		AliasFile:         c.String("alias_file"),
+		PayloadProvider:   c.String("payload_provider"),
+		Payload: PayloadSource{
+			File:    c.String("json_file_name"),
+			Content: c.String("json_content"),
+			Stdin:   c.Bool("json_stdin"),
+			Env:     c.String("json_env"),
+		},
+		WebhookSecret:    c.String("webhook_secret"),
+		WebhookSignature: c.String("webhook_signature"),
+		MarkdownMaxSize:  c.Int("markdown_max_size"),
+		Formats:          c.StringSlice("format"),
+		Template:         c.String("template"),
 	}
 
 	plugin := Plugin{Config: config}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"commit-insights/internal/report"
+)
+
+// DefaultMarkdownMaxSize is the size limit of GitHub comments, the smallest of
+// the places the report is meant for (comments, job summaries, wikis).
+const DefaultMarkdownMaxSize = 65536
+
+// markdownReserve is kept free at every point of the report for the notes
+// telling what was left out.
+const markdownReserve = 512
+
+// fileStatusEmojis marks the git status letters in the Markdown report.
+var fileStatusEmojis = map[string]string{
+	"A": "🟢",
+	"M": "🟡",
+	"D": "🔴",
+	"R": "🔵",
+	"C": "🟣",
+	"T": "🟤",
+}
+
+// refActionEmojis marks the actions of the refs of a push.
+var refActionEmojis = map[string]string{
+	"created": "🆕",
+	"updated": "🔄",
+	"deleted": "🗑️",
+}
+
+// RenderMarkdown renders the insights as a Markdown report: a summary, the
+// committers, a file table and collapsible commit details. When the report
+// would exceed maxSize bytes (0 means no limit), the committers, files and
+// commits that do not fit are left out and a note tells how many.
+func RenderMarkdown(r *report.Report, maxSize int) string {
+	md := &markdownBuilder{maxSize: maxSize}
+
+	title := "Commit Insights: " + r.Repository.Name
+	if r.Repository.Branch != "" {
+		title += " (" + r.Repository.Branch + ")"
+	}
+	md.WriteString("## " + escapeMarkdown(title) + "\n\n")
+
+	stats := r.Stats()
+	md.WriteString("| Build | Commits | Files | Lines | Authors | Merged PRs |\n")
+	md.WriteString("|-------|---------|-------|-------|---------|------------|\n")
+	md.WriteString(fmt.Sprintf("| %s | %d | %d | +%d −%d | %d | %d |\n\n", escapeMarkdown(r.Repository.BuildType), stats.Commits, stats.FilesChanged, stats.Additions, stats.Deletions, stats.Authors, stats.MergedPRs))
+
+	if pipeline := r.Baseline; pipeline != nil {
+		line := fmt.Sprintf("**Baseline:** %s (%s)", escapeMarkdown(pipeline.Name), escapeMarkdown(pipeline.Status))
+		if pipeline.StartedTime != "" {
+			line += ", started " + escapeMarkdown(pipeline.StartedTime)
+		}
+		if r.ExecutionURL != "" {
+			line += fmt.Sprintf(" · [Current execution](%s)", escapeMarkdown(r.ExecutionURL))
+		}
+		md.WriteString(line + "  \n")
+	}
+	if r.Range.Old != "" || r.Range.New != "" {
+		md.WriteString(fmt.Sprintf("**Range:** `%s`..`%s`\n", report.ShortHash(r.Range.Old), report.ShortHash(r.Range.New)))
+	}
+	md.WriteString("\n")
+
+	if len(r.Range.Refs) > 0 {
+		md.WriteString("### Refs\n\n")
+		for _, ref := range r.Range.Refs {
+			line := fmt.Sprintf("- %s %s **%s** %s", refActionEmojis[ref.Action], ref.Type, escapeMarkdown(ref.Name), ref.Action)
+			if ref.Release {
+				line += " 🏷️ release"
+			}
+			md.WriteString(line + "\n")
+		}
+		md.WriteString("\n")
+	}
+
+	authors := r.Authors()
+	if len(authors) > 0 && md.Section("### Committers\n\n", len(authors), "committers") {
+		for i, author := range authors {
+			name := escapeMarkdown(author.Name)
+			if author.Username != "" && author.Username != author.Name {
+				name += " (@" + escapeMarkdown(author.Username) + ")"
+			}
+			if !md.Add(fmt.Sprintf("- %s: %s, +%d −%d\n", name, plural(author.Commits, "commit"), author.Additions, author.Deletions)) {
+				md.Omitted(len(authors)-i, "committers")
+				break
+			}
+		}
+		md.WriteString("\n")
+	}
+
+	fileHeader := "### Files Changed\n\n" +
+		"🟢 added · 🟡 modified · 🔴 deleted · 🔵 renamed · 🟣 copied\n\n" +
+		"| | File | Lines | Commits |\n" +
+		"|---|------|-------|---------|\n"
+	files := r.Files()
+	if len(files) > 0 && md.Section(fileHeader, len(files), "files") {
+		for i, file := range files {
+			lines := fmt.Sprintf("+%d −%d", file.Additions, file.Deletions)
+			if file.Binary {
+				lines = "binary"
+			}
+			row := fmt.Sprintf("| %s | `%s` | %s | %d |\n", statusEmoji(file.Status), escapeTableCode(file.Path), lines, len(file.Commits))
+			if !md.Add(row) {
+				md.Omitted(len(files)-i, "files")
+				break
+			}
+		}
+		md.WriteString("\n")
+	}
+
+	if len(r.Commits) > 0 && md.Section("### Commits\n\n", len(r.Commits), "commits") {
+		for i, commit := range r.Commits {
+			if !md.Add(markdownCommit(commit)) {
+				md.Omitted(len(r.Commits)-i, "commits")
+				break
+			}
+		}
+	}
+
+	return md.String()
+}
+
+// markdownCommit renders a commit as a collapsible block with its message,
+// people and file changes.
+func markdownCommit(commit report.CommitEntry) string {
+	var b strings.Builder
+
+	author := html.EscapeString(commit.Author.Name)
+	b.WriteString(fmt.Sprintf("<details>\n<summary><code>%s</code> %s · %s", commit.ShortHash(), html.EscapeString(commit.Title), author))
+	if commit.Merge {
+		b.WriteString(" · merge")
+	}
+	b.WriteString(fmt.Sprintf(" · +%d −%d</summary>\n\n", commit.Additions, commit.Deletions))
+
+	if commit.Body != "" {
+		for _, line := range strings.Split(commit.Body, "\n") {
+			b.WriteString("> " + escapeMarkdown(line) + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if !commit.Time.IsZero() {
+		b.WriteString("- **Time:** " + commit.Time.UTC().Format(time.RFC3339) + "\n")
+	}
+	if len(commit.CoAuthors) > 0 {
+		b.WriteString("- **Co-authors:** " + markdownPeople(commit.CoAuthors) + "\n")
+	}
+	if len(commit.ReviewedBy) > 0 {
+		b.WriteString("- **Reviewed by:** " + markdownPeople(commit.ReviewedBy) + "\n")
+	}
+	b.WriteString("\n")
+
+	if len(commit.Changes) > 0 {
+		b.WriteString("| | File | Lines |\n")
+		b.WriteString("|---|------|-------|\n")
+		for _, change := range commit.Changes {
+			path := "`" + escapeTableCode(change.Path) + "`"
+			if change.OldPath != "" {
+				path = "`" + escapeTableCode(change.OldPath) + "` → " + path
+			}
+			lines := fmt.Sprintf("+%d −%d", change.Additions, change.Deletions)
+			if change.Binary {
+				lines = "binary"
+			}
+			b.WriteString(fmt.Sprintf("| %s | %s | %s |\n", statusEmoji(change.Status), path, lines))
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("</details>\n\n")
+	return b.String()
+}
+
+// markdownBuilder is a strings.Builder that refuses optional content once the
+// report reaches its size limit, keeping room for the notes of what was left
+// out.
+type markdownBuilder struct {
+	strings.Builder
+	maxSize   int
+	truncated bool
+}
+
+// Add writes s when it fits in the size limit. Once something was left out
+// nothing else is added, so the report never skips to smaller items.
+func (b *markdownBuilder) Add(s string) bool {
+	if b.truncated || (b.maxSize > 0 && b.Len()+len(s)+markdownReserve > b.maxSize) {
+		return false
+	}
+	b.WriteString(s)
+	return true
+}
+
+// Section starts a section of count items with its header, or notes that the
+// whole section was left out.
+func (b *markdownBuilder) Section(header string, count int, items string) bool {
+	if !b.Add(header) {
+		b.Omitted(count, items)
+		return false
+	}
+	return true
+}
+
+// Omitted notes that count items were left out.
+func (b *markdownBuilder) Omitted(count int, items string) {
+	b.WriteString(fmt.Sprintf("\n_… %d more %s not shown, the report was truncated to fit %d bytes._\n", count, items, b.maxSize))
+	b.truncated = true
+}
+
+func statusEmoji(status string) string {
+	if emoji, ok := fileStatusEmojis[status]; ok {
+		return emoji
+	}
+	return "⚪"
+}
+
+func markdownPeople(people []report.Person) string {
+	names := make([]string, 0, len(people))
+	for _, person := range people {
+		names = append(names, escapeMarkdown(person.Name))
+	}
+	return strings.Join(names, ", ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// markdownEscaper escapes the characters that would be read as Markdown or
+// HTML in free text such as names and commit messages.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+	"|", `\|`,
+	"<", "&lt;",
+	">", "&gt;",
+)
+
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// escapeTableCode escapes a code span inside a table cell, where only the
+// pipe needs escaping.
+func escapeTableCode(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "`", "'")
+}
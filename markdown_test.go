@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"commit-insights/internal/report"
+)
+
+func TestRenderMarkdownEscaping(t *testing.T) {
+	r := &report.Report{
+		Repository:   report.Repository{Name: "commit-insights", Branch: "main", BuildType: "push"},
+		Baseline:     &report.PipelineRef{Name: "Build", Status: "Success"},
+		ExecutionURL: "https://harness.example.com/executions/a|b*c]d",
+		Range: report.Range{
+			Refs: []report.RefUpdate{{Ref: "refs/heads/fix|*weird]", Name: "fix|*weird]", Type: "branch", Action: "created", NewSHA: "abc"}},
+		},
+	}
+
+	md := RenderMarkdown(r, 0)
+	for _, want := range []string{
+		`[Current execution](https://harness.example.com/executions/a\|b\*c\]d)`,
+		`- 🆕 branch **fix\|\*weird\]** created`,
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("report does not contain %q:\n%s", want, md)
+		}
+	}
+	for _, raw := range []string{"a|b*c]d", "fix|*weird]"} {
+		if strings.Contains(md, raw) {
+			t.Errorf("report contains %q unescaped:\n%s", raw, md)
+		}
+	}
+}
+
+func TestRenderMarkdownTruncation(t *testing.T) {
+	r := &report.Report{Repository: report.Repository{Name: "commit-insights", Branch: "main", BuildType: "push"}}
+	for i := 0; i < 200; i++ {
+		author := report.Person{Name: fmt.Sprintf("Author %d", i), Email: fmt.Sprintf("author%d@example.com", i)}
+		r.Commits = append(r.Commits, report.CommitEntry{
+			Hash:      fmt.Sprintf("%040d", i),
+			Title:     fmt.Sprintf("Change file %d", i),
+			Author:    author,
+			Committer: author,
+			Additions: 1,
+			Changes:   []report.FileChange{{Path: fmt.Sprintf("pkg/file%03d.go", i), Status: "M", Additions: 1}},
+		})
+	}
+
+	for _, maxSize := range []int{1024, 2048, 4096, 16384} {
+		md := RenderMarkdown(r, maxSize)
+		if len(md) > maxSize {
+			t.Errorf("max size %d: got %d bytes", maxSize, len(md))
+		}
+		notice := fmt.Sprintf(" not shown, the report was truncated to fit %d bytes._\n", maxSize)
+		if !strings.HasSuffix(md, notice) {
+			t.Errorf("max size %d: report does not end with the truncation notice:\n%s", maxSize, md)
+		}
+	}
+
+	if md := RenderMarkdown(r, 0); strings.Contains(md, "truncated") {
+		t.Error("report truncated without a size limit")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// legacyPayloadFile is read when no payload source is configured, for
+// pipelines written before the sources were configurable.
+const legacyPayloadFile = "bitbucket/payload-bitbucket-harness.json"
+
+// PayloadSource says where the webhook payload is read from in payload mode.
+// At most one source may be set.
+type PayloadSource struct {
+	// File is the path of a JSON file.
+	File string
+	// Content is the payload itself.
+	Content string
+	// Stdin reads the payload from the standard input.
+	Stdin bool
+	// Env is the name of an environment variable holding the payload, e.g.
+	// one set to <+trigger.payload> in Harness.
+	Env string
+}
+
+// Validate checks that at most one source is set.
+func (s PayloadSource) Validate() error {
+	var sources []string
+	if s.File != "" {
+		sources = append(sources, "json_file_name")
+	}
+	if s.Content != "" {
+		sources = append(sources, "json_content")
+	}
+	if s.Stdin {
+		sources = append(sources, "json_stdin")
+	}
+	if s.Env != "" {
+		sources = append(sources, "json_env")
+	}
+	if len(sources) > 1 {
+		return fmt.Errorf("please specify only one payload source, got %s", strings.Join(sources, ", "))
+	}
+	return nil
+}
+
+// Read returns the payload. stdin is only read when the Stdin source is set.
+func (s PayloadSource) Read(stdin io.Reader) ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	var payload []byte
+	var err error
+	switch {
+	case s.File != "":
+		fmt.Println("| Payload Source: file", s.File)
+		payload, err = os.ReadFile(s.File)
+	case s.Content != "":
+		fmt.Println("| Payload Source: json_content")
+		payload = []byte(s.Content)
+	case s.Stdin:
+		fmt.Println("| Payload Source: stdin")
+		payload, err = io.ReadAll(stdin)
+	case s.Env != "":
+		fmt.Println("| Payload Source: environment variable", s.Env)
+		value, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return nil, fmt.Errorf("payload environment variable %s is not set", s.Env)
+		}
+		payload = []byte(value)
+	default:
+		if _, statErr := os.Stat(legacyPayloadFile); statErr != nil {
+			return nil, errors.New("no payload source, set one of json_file_name, json_content, json_stdin or json_env")
+		}
+		fmt.Printf("| \033[33m[WARNING] - No payload source set, reading %s. Set json_file_name instead.\033[0m\n", legacyPayloadFile)
+		payload, err = os.ReadFile(legacyPayloadFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading payload: %w", err)
+	}
+
+	if strings.TrimSpace(string(payload)) == "" {
+		return nil, errors.New("the payload is empty")
+	}
+	return payload, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPayloadSourceValidate(t *testing.T) {
+	tests := []struct {
+		source  PayloadSource
+		wantErr string
+	}{
+		{PayloadSource{}, ""},
+		{PayloadSource{File: "payload.json"}, ""},
+		{PayloadSource{Env: "PAYLOAD"}, ""},
+		{PayloadSource{File: "payload.json", Content: "{}"}, "got json_file_name, json_content"},
+		{PayloadSource{Stdin: true, Env: "PAYLOAD"}, "got json_stdin, json_env"},
+		{PayloadSource{File: "payload.json", Content: "{}", Stdin: true, Env: "PAYLOAD"}, "got json_file_name, json_content, json_stdin, json_env"},
+	}
+
+	for _, tt := range tests {
+		err := tt.source.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Validate(%+v) = %v", tt.source, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Validate(%+v) = %v, want %q", tt.source, err, tt.wantErr)
+		}
+	}
+
+	// Read refuses several sources too, without reading any.
+	if _, err := (PayloadSource{Content: "{}", Stdin: true}).Read(strings.NewReader("{}")); err == nil {
+		t.Error("Read succeeded with two sources")
+	}
+}
+
+func TestPayloadSourceRead(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "payload.json")
+	writeFile(t, file, `{"source":"file"}`)
+	writeFile(t, filepath.Join(dir, "empty.json"), " \n")
+	t.Setenv("TEST_PAYLOAD", `{"source":"env"}`)
+	t.Setenv("TEST_EMPTY_PAYLOAD", "")
+
+	tests := []struct {
+		name    string
+		source  PayloadSource
+		stdin   string
+		want    string
+		wantErr string
+	}{
+		{name: "file", source: PayloadSource{File: file}, want: `{"source":"file"}`},
+		{name: "content", source: PayloadSource{Content: `{"source":"content"}`}, want: `{"source":"content"}`},
+		{name: "stdin", source: PayloadSource{Stdin: true}, stdin: `{"source":"stdin"}`, want: `{"source":"stdin"}`},
+		{name: "env", source: PayloadSource{Env: "TEST_PAYLOAD"}, stdin: `{"source":"stdin"}`, want: `{"source":"env"}`},
+		{name: "unset env", source: PayloadSource{Env: "TEST_MISSING_PAYLOAD"}, wantErr: "payload environment variable TEST_MISSING_PAYLOAD is not set"},
+		{name: "empty env", source: PayloadSource{Env: "TEST_EMPTY_PAYLOAD"}, wantErr: "the payload is empty"},
+		{name: "empty stdin", source: PayloadSource{Stdin: true}, stdin: "", wantErr: "the payload is empty"},
+		{name: "blank file", source: PayloadSource{File: filepath.Join(dir, "empty.json")}, wantErr: "the payload is empty"},
+		{name: "missing file", source: PayloadSource{File: filepath.Join(dir, "missing.json")}, wantErr: "reading payload"},
+		{name: "unreadable file", source: PayloadSource{File: dir}, wantErr: "reading payload"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := tt.source.Read(strings.NewReader(tt.stdin))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got payload %q, error %v, want %q", payload, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(payload) != tt.want {
+				t.Errorf("got payload %q, want %q", payload, tt.want)
+			}
+		})
+	}
+
+	_, err := PayloadSource{File: filepath.Join(dir, "missing.json")}.Read(nil)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("got error %v for a missing file, want fs.ErrNotExist", err)
+	}
+}
+
+func TestPayloadSourceLegacyFile(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if _, err := (PayloadSource{}).Read(nil); err == nil || !strings.Contains(err.Error(), "no payload source") {
+		t.Errorf("got error %v without any source, want no payload source", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(legacyPayloadFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, legacyPayloadFile, `{"source":"legacy"}`)
+	payload, err := PayloadSource{}.Read(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != `{"source":"legacy"}` {
+		t.Errorf("got payload %q from the legacy file", payload)
+	}
+}
@@ -1,17 +1,16 @@
 package main
 
 import (
-	"io"
-
+	htmlgenerator "commit-insights/internal/generators"
 	"commit-insights/internal/models"
+	"commit-insights/internal/payloads"
+	"commit-insights/internal/report"
 
-	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
-	"log"
-	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -19,19 +18,39 @@ import (
 
 type (
 	Config struct {
-		AccID            string   `json:"accID"`
-		OrgID            string   `json:"orgID"`
-		ProjectID        string   `json:"projectID"`
-		PipelineID       string   `json:"pipelineID"`
-		StageID          string   `json:"stageID"`
-		StatusList       []string `json:"statusList"`
-		RepoName         string   `json:"repoName"`
-		Branch           string   `json:"branch"`
-		BuildType        string   `json:"buildType"`
-		IngestionType    string   `json:"ingestionType"`
-		CommitID         string   `json:"commitID"`
-		HarnessSecret    string   `json:"harnessSecret"`
-		PipeExecutionURL string   `json:"harnessPipeExecutionURL"`
+		AccID             string        `json:"accID"`
+		OrgID             string        `json:"orgID"`
+		ProjectID         string        `json:"projectID"`
+		PipelineID        string        `json:"pipelineID"`
+		StageIDs          []string      `json:"stageIDs"`
+		ExecutionID       string        `json:"executionID"`
+		StatusList        []string      `json:"statusList"`
+		RepoName          string        `json:"repoName"`
+		Branch            string        `json:"branch"`
+		BuildType         string        `json:"buildType"`
+		IngestionType     string        `json:"ingestionType"`
+		CommitID          string        `json:"commitID"`
+		HarnessSecret     string        `json:"harnessSecret"`
+		PipeExecutionURL  string        `json:"harnessPipeExecutionURL"`
+		GitBackend        string        `json:"gitBackend"`
+		MaxFetchDepth     int           `json:"maxFetchDepth"`
+		RangeStrategy     string        `json:"rangeStrategy"`
+		TargetBranch      string        `json:"targetBranch"`
+		MergeDiffs        bool          `json:"mergeDiffs"`
+		AliasFile         string        `json:"aliasFile"`
+		PayloadProvider   string        `json:"payloadProvider"`
+		Payload           PayloadSource `json:"payload"`
+		WebhookSecret     string        `json:"webhookSecret"`
+		WebhookSignature  string        `json:"webhookSignature"`
+		HarnessURL        string        `json:"harnessURL"`
+		HarnessCABundle   string        `json:"harnessCABundle"`
+		HarnessProxy      string        `json:"harnessProxy"`
+		HarnessTimeout    time.Duration `json:"harnessTimeout"`
+		HarnessMaxRetries int           `json:"harnessMaxRetries"`
+		LookbackLimit     int           `json:"lookbackLimit"`
+		MarkdownMaxSize   int           `json:"markdownMaxSize"`
+		Formats           []string      `json:"formats"`
+		Template          string        `json:"template"`
 	}
 
 	Plugin struct {
@@ -43,142 +62,180 @@ var plugin Plugin
 
 const lineBreak = "|---------------------------------------------"
 
-func getLastSuccessfulExecution(accID string, orgID string, projectID string, pipelineID string, stageID string, statusList []string, repoName string, branch string, buildType string) (string, string, models.Pipeline, error) {
-
-	url := "https://app.harness.io/pipeline/api/pipelines/execution/summary?page=0&size=1&accountIdentifier=" + accID + "&orgIdentifier=" + orgID + "&projectIdentifier=" + projectID + "&pipelineIdentifier=" + pipelineID + ""
-	method := "POST"
-
-	var statusListJson string
-	statusListJsonBytes, err := json.Marshal(statusList)
-	if err != nil {
-		return "", "", models.Pipeline{}, err
-	}
-	statusListJson = string(statusListJsonBytes)
+// executionPageSize is the number of executions requested per page when
+// looking for the baseline execution.
+const executionPageSize = 10
+
+// getLastSuccessfulExecution walks back through the executions matching the
+// filters, newest first, and returns the commits of the first one (at most
+// lookbackLimit executions are examined) that has any.
+//
+// Without stageIDs the execution itself must have one of the statusList
+// statuses. With stageIDs the execution status is ignored and every listed
+// stage must have reached one of them instead, so that e.g. an execution
+// whose build stage passed but deploy stage failed can be the baseline.
+// currentExecutionID, the running execution, is never chosen.
+func getLastSuccessfulExecution(client *HarnessClient, lookbackLimit int, accID string, orgID string, projectID string, pipelineID string, stageIDs []string, statusList []string, currentExecutionID string, repoName string, branch string, buildType string) (string, string, models.Pipeline, error) {
 
 	if buildType == "push" {
 		buildType = "branch"
 	} else if buildType == "pull_request" {
 		buildType = "PR"
 	}
-	payload := strings.NewReader(fmt.Sprintf(`{"status":%s,"moduleProperties":{"ci":{"buildType":"%s","branch":"%s","repoName":"%s"}},"filterType":"PipelineExecution"}`, statusListJson, buildType, branch, repoName))
-
-	client := &http.Client{}
-	req, err := http.NewRequest(method, url, payload)
 
-	if err != nil {
-		fmt.Println(err)
-		return "", "", models.Pipeline{}, err
+	var filter ExecutionSummaryFilter
+	if len(stageIDs) == 0 {
+		filter.Status = statusList
 	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Add("x-api-key", plugin.Config.HarnessSecret)
-	// fmt.Println(url)
-	// fmt.Println(payload)
+	filter.ModuleProperties.CI.BuildType = buildType
+	filter.ModuleProperties.CI.Branch = branch
+	filter.ModuleProperties.CI.RepoName = repoName
 
-	res, err := client.Do(req)
-	if err != nil {
-		fmt.Println("Error finding last successful execution")
-		fmt.Println("URL: ", url)
-		fmt.Println("Payload: ", payload)
-		fmt.Println("Error: ", err)
-		fmt.Println("Status: ", req.Response.Status)
-		return "", "", models.Pipeline{}, err
+	if lookbackLimit <= 0 {
+		lookbackLimit = 1
 	}
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		fmt.Println("Error parsing response")
-		fmt.Println("URL: ", url)
-		fmt.Println("Payload: ", payload)
-		fmt.Println("Response: ", body)
-		fmt.Println("Error: ", err)
-		fmt.Println("Status: ", req.Response.Status)
-		return "", "", models.Pipeline{}, err
-	}
-	// fmt.Println(string(body))
+	scope := HarnessScope{AccountID: accID, OrgID: orgID, ProjectID: projectID}
+	examined := 0
+	for page := 0; examined < lookbackLimit; page++ {
+		response, err := client.ExecutionSummary(scope, pipelineID, page, executionPageSize, filter)
+		if err != nil {
+			fmt.Println("Error finding last successful execution")
+			fmt.Println("Harness URL: ", client.BaseURL())
+			fmt.Println("Error: ", err)
+			return "", "", models.Pipeline{}, err
+		}
 
-	defer res.Body.Close()
+		for _, content := range response.Data.Content {
+			if examined >= lookbackLimit {
+				break
+			}
+			examined++
 
-	var response models.Response
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return "", "", models.Pipeline{}, errors.New("error parsing JSON")
+			fmt.Printf("| Found execution with status:\033[0m \033[1;32m%s\033[0m\n", content.Status)
+			fmt.Println(lineBreak)
+			fmt.Printf("| \033[1;36mPlan Execution ID:\033[0m \033[1;32m%s\033[0m\n", content.PlanExecutionId)
+			fmt.Printf("| \033[1;36mPipeline Name:\033[0m \033[1;32m%s\033[0m\n", content.Name)
+			fmt.Printf("| \033[1;36mPipe Status:\033[0m \033[1;32m%s\033[0m\n", content.Status)
+			fmt.Println(lineBreak)
+
+			for _, nodeInfo := range content.LayoutNodeMap {
+				fmt.Println(lineBreak)
+				fmt.Printf("| \033[1;36mNode Type:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.NodeType)
+				fmt.Printf("| \033[1;36mNode Group:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.NodeGroup)
+				fmt.Printf("| \033[1;36mNode Identifier:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.NodeIdentifier)
+				fmt.Printf("| \033[1;36mName:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.Name)
+				fmt.Printf("| \033[1;36mNode UUID:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.NodeUuid)
+				fmt.Printf("| \033[1;36mStatus:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.Status)
+				fmt.Printf("| \033[1;36mModule:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.Module)
+				fmt.Printf("| \033[1;36mStart TS:\033[0m \033[1;32m%s\033[0m\n", time.Unix(int64(nodeInfo.StartTs/1000), 0))
+				fmt.Printf("| \033[1;36mEnd TS:\033[0m \033[1;32m%s\033[0m\n", time.Unix(int64(nodeInfo.EndTs/1000), 0))
+				fmt.Printf("| \033[1;36mFailure Info:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.FailureInfo.Message)
+				fmt.Printf("| \033[1;36mEdge Layout List:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.EdgeLayoutList)
+				fmt.Println(lineBreak)
+			}
+
+			if currentExecutionID != "" && content.PlanExecutionId == currentExecutionID {
+				fmt.Println("| \033[33mSkipping the current execution\033[0m")
+				fmt.Println(lineBreak)
+				continue
+			}
+			if reason := unmetStage(content.LayoutNodeMap, stageIDs, statusList); reason != "" {
+				fmt.Printf("| \033[33m%s, looking further back...\033[0m\n", reason)
+				fmt.Println(lineBreak)
+				continue
+			}
+
+			var commits []string
+			for _, commit := range content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits {
+				if commit.ID != "" {
+					commits = append(commits, commit.ID)
+				}
+			}
+			fmt.Printf("| \033[1;36mNumber of commits:\033[0m \033[1;32m%d\033[0m\n", len(commits))
+			fmt.Println(lineBreak)
+
+			if len(commits) == 0 {
+				fmt.Println("| \033[33mNo commits found in this execution, looking further back...\033[0m")
+				fmt.Println(lineBreak)
+				continue
+			}
+
+			var graph *models.ExecutionGraph
+			details, err := client.ExecutionDetails(scope, content.PlanExecutionId)
+			if err != nil {
+				fmt.Println("| \033[33mCould not fetch the execution graph, steps will be missing:\033[0m", err)
+			} else {
+				graph = &details.Data.ExecutionGraph
+			}
+
+			pipeline, err := parsePipeline(content, graph)
+			if err != nil {
+				return "", "", models.Pipeline{}, err
+			}
+
+			firstCommit := commits[0]
+			lastCommit := commits[len(commits)-1]
+
+			return firstCommit, lastCommit, *pipeline, nil
+		}
+
+		if len(response.Data.Content) < executionPageSize || page+1 >= response.Data.TotalPages {
+			break
+		}
 	}
 
-	if len(response.Data.Content) == 0 {
+	if examined == 0 {
 		return "", "", models.Pipeline{}, errors.New("no successful execution found")
 	}
+	return "", "", models.Pipeline{}, fmt.Errorf("no successful execution with commits found in the last %d executions", examined)
+}
 
-	responseBytes, err := json.Marshal(response)
+// getExecution fetches the pipeline model of a single execution, e.g. the one
+// running the plugin.
+func getExecution(client *HarnessClient, accID string, orgID string, projectID string, executionID string) (models.Pipeline, error) {
+	scope := HarnessScope{AccountID: accID, OrgID: orgID, ProjectID: projectID}
+	details, err := client.ExecutionDetails(scope, executionID)
 	if err != nil {
-		return "", "", models.Pipeline{}, err
+		return models.Pipeline{}, err
 	}
-	pipelineParsed, err := parsePipeline(responseBytes)
+
+	pipeline, err := parsePipeline(details.Data.PipelineExecutionSummary, &details.Data.ExecutionGraph)
 	if err != nil {
-		return "", "", models.Pipeline{}, err
+		return models.Pipeline{}, err
 	}
-	fmt.Println(pipelineParsed)
-
-	var pipeline models.Pipeline
-	for _, content := range response.Data.Content {
-		fmt.Printf("| Found execution with status:\033[0m \033[1;32m%s\033[0m\n", content.Status)
-		fmt.Println(lineBreak)
-		fmt.Printf("| \033[1;36mPlan Execution ID:\033[0m \033[1;32m%s\033[0m\n", content.PlanExecutionId)
-		fmt.Printf("| \033[1;36mPipeline Name:\033[0m \033[1;32m%s\033[0m\n", content.Name)
-		fmt.Printf("| \033[1;36mPipe Status:\033[0m \033[1;32m%s\033[0m\n", content.Status)
-		fmt.Println(lineBreak)
+	return *pipeline, nil
+}
 
-		pipeline = models.Pipeline{
-			Name:        content.Name,
-			Status:      content.Status,
-			StartedTime: time.Unix(int64(content.StartTs/1000), 0).String(),
-			Duration:    time.Unix(int64(content.EndTs/1000), 0).Sub(time.Unix(int64(content.StartTs/1000), 0)).String(),
-			StageCount:  0,
-			StepCount:   0,
-			Message:     "",
+// unmetStage checks that every stage of stageIDs, matched by identifier or
+// name, has one of the given statuses in the layout of an execution. It
+// returns why the execution does not qualify, or an empty string if it does.
+func unmetStage(layout models.LayoutNodeMap, stageIDs []string, statusList []string) string {
+	for _, stageID := range stageIDs {
+		var stage models.NodeInfo
+		found := false
+		for _, node := range layout {
+			if node.NodeGroup == "STAGE" && (node.NodeIdentifier == stageID || node.Name == stageID) {
+				stage, found = node, true
+				break
+			}
 		}
-
-		// content := response.Data.Content[0]
-		// foundSuccessfulExecution := false
-		for _, nodeInfo := range content.LayoutNodeMap {
-			fmt.Println(lineBreak)
-			fmt.Printf("| \033[1;36mNode Type:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.NodeType)
-			fmt.Printf("| \033[1;36mNode Group:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.NodeGroup)
-			fmt.Printf("| \033[1;36mNode Identifier:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.NodeIdentifier)
-			fmt.Printf("| \033[1;36mName:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.Name)
-			fmt.Printf("| \033[1;36mNode UUID:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.NodeUuid)
-			fmt.Printf("| \033[1;36mStatus:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.Status)
-			fmt.Printf("| \033[1;36mModule:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.Module)
-			fmt.Printf("| \033[1;36mStart TS:\033[0m \033[1;32m%s\033[0m\n", time.Unix(int64(nodeInfo.StartTs/1000), 0))
-			fmt.Printf("| \033[1;36mEnd TS:\033[0m \033[1;32m%s\033[0m\n", time.Unix(int64(nodeInfo.EndTs/1000), 0))
-			fmt.Printf("| \033[1;36mFailure Info:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.FailureInfo.Message)
-			fmt.Printf("| \033[1;36mEdge Layout List:\033[0m \033[1;32m%s\033[0m\n", nodeInfo.EdgeLayoutList)
-			fmt.Println(lineBreak)
+		if !found {
+			return fmt.Sprintf("Stage %s did not run in this execution", stageID)
 		}
 
-		// fmt.Printf("| \033[1;36mStage ID:\033[0m \033[1;32m%s\033[0m\n", stageID)
-
-		var commiters []string
-
-		for _, commit := range content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits {
-			commiters = append(commiters, commit.ID)
+		reached := false
+		for _, status := range statusList {
+			if strings.EqualFold(stage.Status, status) {
+				reached = true
+				break
+			}
 		}
-		// fmt.Printf("Commits: %s\n", strings.Join(commiters, ", "))
-		fmt.Printf("| \033[1;36mNumber of commits:\033[0m \033[1;32m%d\033[0m\n", len(content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits))
-		fmt.Println(lineBreak)
-		// fmt.Printf("Last Commit SHA: %s\n", content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits[len(content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits)-1].ID)
-
-		if len(content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits) > 0 {
-			// fmt.Printf("First Commit SHA: %s\n", content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits[0].ID)
-		} else {
-			fmt.Println("No commits found")
+		if !reached {
+			return fmt.Sprintf("Stage %s finished with status %s", stageID, stage.Status)
 		}
-		firstCommit := content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits[0].ID
-		lastCommit := content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits[len(content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits)-1].ID
-
-		return firstCommit, lastCommit, pipeline, nil
 	}
-
-	return "", "", models.Pipeline{}, errors.New("no successful execution found")
+	return ""
 }
 
 func (p *Plugin) Exec() error {
@@ -198,12 +255,13 @@ func (p *Plugin) Exec() error {
 	var orgID string = p.Config.OrgID
 	var projectID string = p.Config.ProjectID
 	var pipelineID string = p.Config.PipelineID
-	var stageID string = p.Config.StageID
+	var stageIDs []string = p.Config.StageIDs
 	var statusList []string = p.Config.StatusList
 	var repoName string = p.Config.RepoName
 	var branch string = p.Config.Branch
 	var buildType string = p.Config.BuildType
 	var commitID string = p.Config.CommitID
+	var targetBranch string = p.Config.TargetBranch
 
 	fmt.Println(lineBreak)
 	fmt.Println("| \033[1;36mHarness Commit Insights\033[0m")
@@ -214,7 +272,7 @@ func (p *Plugin) Exec() error {
 	fmt.Printf("| \033[1;36mOrg ID:\033[0m \033[1;32m%s\033[0m\n", orgID)
 	fmt.Printf("| \033[1;36mProject ID:\033[0m \033[1;32m%s\033[0m\n", projectID)
 	fmt.Printf("| \033[1;36mPipeline ID:\033[0m \033[1;32m%s\033[0m\n", pipelineID)
-	fmt.Printf("| \033[1;36mStage ID:\033[0m \033[1;32m%s\033[0m\n", stageID)
+	fmt.Printf("| \033[1;36mStage IDs:\033[0m \033[1;32m%s\033[0m\n", stageIDs)
 	fmt.Printf("| \033[1;36mStatus List:\033[0m \033[1;32m%s\033[0m\n", statusList)
 	fmt.Println(lineBreak)
 	fmt.Printf("| \033[1;36mRepo Name:\033[0m \033[1;32m%s\033[0m\n", repoName)
@@ -227,27 +285,87 @@ func (p *Plugin) Exec() error {
 	// You can include a parameter here to select the source (payload or pipeline) for the commit hashes
 	// source := "payload" // or "pipeline"
 	source := p.Config.IngestionType
+	if err := p.Config.Payload.Validate(); err != nil {
+		return err
+	}
+	outputs, err := ParseOutputs(p.Config.Formats)
+	if err != nil {
+		return err
+	}
+	var reportTemplate *ReportTemplate
+	if p.Config.Template != "" {
+		reportTemplate, err = LoadReportTemplate(p.Config.Template)
+		if err != nil {
+			fmt.Printf("| \033[33m[WARNING] - Failed to load the template %s, using the built-in template: %v\033[0m\n", p.Config.Template, err)
+			fmt.Println(lineBreak)
+		}
+	}
 
 	var oldCommitHash, newCommitHash, branchName, repoNamePayload string
-	var err error
 	var isPrivate bool
 	var pipeline models.Pipeline
+	// baseline is the execution the range starts from, when one was found.
+	var baseline *models.Pipeline
+	var dashboards []htmlgenerator.Dashboard
+	// refChanges lists the refs updated by a push payload, each with its
+	// own range.
+	var refChanges []payloads.RefChange
+	var defaultBranch string
 
 	if source == "payload" {
 		fmt.Println(lineBreak)
 		fmt.Println("| \033[1;36mParsing payload...\033[0m")
 		fmt.Println(lineBreak)
-		// Read json fro file
-		payload, err := os.ReadFile("bitbucket/payload-bitbucket-harness.json")
+		payload, err := p.Config.Payload.Read(os.Stdin)
 		if err != nil {
-			log.Fatalf("Error reading file: %v", err)
+			return err
 		}
 
-		// Get the old and new commit hashes from the payload/pipeline
-		oldCommitHash, newCommitHash, branchName, repoNamePayload, isPrivate, err = ParsePayload(payload)
+		parser, err := payloads.Lookup(payload, p.Config.PayloadProvider)
 		if err != nil {
-			log.Fatalf("Error parsing payload: %v", err)
+			return err
 		}
+
+		if p.Config.WebhookSecret != "" {
+			err = payloads.Verify(parser.Provider(), payload, p.Config.WebhookSignature, p.Config.WebhookSecret)
+			if err != nil {
+				fmt.Println("| \033[1;31mThe webhook payload failed signature verification, refusing to generate insights\033[0m")
+				return err
+			}
+			fmt.Println("| \033[1;32mWebhook signature verified\033[0m")
+		} else if p.Config.WebhookSignature != "" {
+			fmt.Println("| \033[33m[WARNING] - A webhook signature was given without webhook_secret, the payload is not verified\033[0m")
+		}
+
+		event, err := parser.Parse(payload)
+		if err != nil {
+			return err
+		}
+
+		oldCommitHash = event.OldSHA
+		newCommitHash = event.NewSHA
+		branchName = event.Branch
+		repoNamePayload = event.Repository.Name
+		isPrivate = event.Repository.Private
+		buildType = event.Kind
+		refChanges = event.Changes
+		defaultBranch = event.Repository.DefaultBranch
+		if len(refChanges) > 0 && refChanges[0].Type == payloads.RefTag && event.Ref == refChanges[0].Ref {
+			buildType = "tag"
+		}
+		if repoName == "" {
+			repoName = event.Repository.Name
+		}
+		if event.PullRequest != nil && targetBranch == "" {
+			targetBranch = event.PullRequest.TargetBranch
+		}
+		// Created branches and most pull request events have no old commit.
+		if oldCommitHash == "" {
+			oldCommitHash = newCommitHash
+		}
+		fmt.Println("| Provider: ", event.Provider)
+		fmt.Println("| Event: ", event.Kind)
+		fmt.Println("| Actor: ", event.Actor.Name)
 		fmt.Println(lineBreak)
 		fmt.Println("| Old Commit Hash: ", oldCommitHash)
 		fmt.Println("| New Commit Hash: ", newCommitHash)
@@ -259,7 +377,7 @@ func (p *Plugin) Exec() error {
 	} else if source == "pipeline" {
 		branchName = branch
 		fmt.Println(lineBreak)
-		fmt.Println("| \033[1;36mGetting last successful execution...\033[0m")
+		fmt.Println("| \033[1;36mGetting the baseline execution...\033[0m")
 		fmt.Println(lineBreak)
 		fmt.Println("| Branch Name: ", branchName)
 		fmt.Println("| Repo Name: ", repoName)
@@ -267,18 +385,54 @@ func (p *Plugin) Exec() error {
 		fmt.Println(lineBreak)
 		// Get the old and new commit hashes from the pipeline
 
-		oldCommitHash, newCommitHash, pipeline, err = getLastSuccessfulExecution(accID, orgID, projectID, pipelineID, stageID, statusList, repoName, branch, buildType)
+		var client *HarnessClient
+		client, err = NewHarnessClient(HarnessClientConfig{
+			BaseURL:    p.Config.HarnessURL,
+			APIKey:     p.Config.HarnessSecret,
+			CABundle:   p.Config.HarnessCABundle,
+			Proxy:      p.Config.HarnessProxy,
+			Timeout:    p.Config.HarnessTimeout,
+			MaxRetries: p.Config.HarnessMaxRetries,
+		})
 		if err != nil {
-			fmt.Println("Error getting last successful execution")
+			return err
+		}
+
+		oldCommitHash, newCommitHash, pipeline, err = getLastSuccessfulExecution(client, p.Config.LookbackLimit, accID, orgID, projectID, pipelineID, stageIDs, statusList, p.Config.ExecutionID, repoName, branch, buildType)
+		if err != nil {
+			fmt.Println("Error getting the baseline execution")
 			fmt.Println(err)
+			switch {
+			case errors.Is(err, ErrHarnessUnauthorized):
+				fmt.Println("| \033[1;31mThe Harness API rejected the credentials, check harness_secret and its permissions\033[0m")
+			case errors.Is(err, ErrHarnessNotFound):
+				fmt.Println("| \033[1;31mThe Harness API could not find the pipeline, check the account, org, project and pipeline identifiers\033[0m")
+			case errors.Is(err, ErrHarnessServer), errors.Is(err, ErrHarnessRateLimited):
+				fmt.Println("| \033[33mThe Harness API is unavailable, retries were exhausted\033[0m")
+			}
+			fmt.Println("| \033[33mFalling back to the current commit only\033[0m")
 			oldCommitHash = commitID
 			newCommitHash = commitID
 			// return err
 
+		} else {
+			baseline = &pipeline
+			dashboards = append(dashboards, htmlgenerator.Dashboard{Title: "Baseline Execution", Pipeline: pipeline})
+		}
+
+		if p.Config.ExecutionID != "" {
+			current, err := getExecution(client, accID, orgID, projectID, p.Config.ExecutionID)
+			if err != nil {
+				fmt.Println("| \033[33mCould not fetch the current execution:\033[0m", err)
+			} else {
+				dashboards = append(dashboards, htmlgenerator.Dashboard{Title: "Current Execution", Pipeline: current})
+			}
 		}
 		fmt.Println(lineBreak)
-		if pipeline.Status == "Success" {
-			fmt.Println("| \033[1;32mLast successful execution found\033[0m")
+		// With stageID the baseline's pipeline may have failed while its
+		// stages met statusList, so check the baseline rather than its status.
+		if baseline != nil {
+			fmt.Println("| \033[1;32mBaseline execution found\033[0m")
 			fmt.Println(lineBreak)
 			fmt.Printf("| \033[1;36mPipeline Name:\033[0m \033[1;32m%s\033[0m\n", pipeline.Name)
 			fmt.Printf("| \033[1;36mPipeline Status:\033[0m \033[1;32m%s\033[0m\n", pipeline.Status)
@@ -289,7 +443,7 @@ func (p *Plugin) Exec() error {
 			fmt.Printf("| \033[1;36mPipeline Message:\033[0m \033[1;32m%s\033[0m\n", pipeline.Message)
 			fmt.Println(lineBreak)
 		} else {
-			fmt.Println("| \033[1;31mLast successful execution not found\033[0m")
+			fmt.Println("| \033[1;31mBaseline execution not found\033[0m")
 			fmt.Println(lineBreak)
 		}
 
@@ -312,111 +466,115 @@ func (p *Plugin) Exec() error {
 	fmt.Println(lineBreak)
 
 	// result, err := GetCommitInfo("e7c79ef9dcaa60c41c88ea5417b977bffe0bdb9f", "HEAD")
-	result, err := GetCommitInfo(oldCommitHash, newCommitHash)
+	backend, err := NewGitBackend(p.Config.GitBackend, ".")
 	if err != nil {
 		fmt.Println(err)
 		return err
 	}
 
-	fmt.Println(lineBreak)
-	fmt.Println("| \033[1;36mGit Commit Info\033[0m")
-	fmt.Println(lineBreak)
-	for _, fileInfo := range result {
-		fmt.Printf("| \033[1;36mFile Name:\033[0m \033[1;32m%s\033[0m\n", fileInfo.FileName)
-		for _, commitInfo := range fileInfo.CommitDetails {
-			fmt.Printf("| \033[1;36mCommit Hash:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Hash)
-			fmt.Printf("| \033[1;36mCommit Name:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Name)
-			fmt.Printf("| \033[1;36mCommit Email:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Email)
-			fmt.Printf("| \033[1;36mCommit Username:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Username)
-			fmt.Printf("| \033[1;36mCommit Author Time:\033[0m \033[1;32m%s\033[0m\n", commitInfo.AuthorTime)
-			fmt.Printf("| \033[1;36mCommit Committer Name:\033[0m \033[1;32m%s\033[0m\n", commitInfo.CommitterName)
-			fmt.Printf("| \033[1;36mCommit Committer Email:\033[0m \033[1;32m%s\033[0m\n", commitInfo.CommitterEmail)
-			fmt.Printf("| \033[1;36mCommit Ref Names:\033[0m \033[1;32m%s\033[0m\n", commitInfo.RefNames)
-			fmt.Printf("| \033[1;36mCommit Title:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Title)
-			fmt.Printf("| \033[1;36mCommit Body:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Body)
-			fmt.Printf("| \033[1;36mCommit Parent Hashes:\033[0m \033[1;32m%s\033[0m\n", commitInfo.ParentHashes)
-			fmt.Println("| \033[1;36mFile Changes:\033[0m")
-			for _, change := range commitInfo.Changes {
-				fmt.Printf("| \033[1;36mFile Name:\033[0m \033[1;32m%s\033[0m | \033[1;36mStatus:\033[0m \033[1;32m%s\033[0m\n", change.FileName, change.Status)
-			}
-			fmt.Println(lineBreak)
-		}
+	ranges, refRanges, err := resolveRanges(backend, p.Config.RangeStrategy, buildType, targetBranch, defaultBranch, oldCommitHash, newCommitHash, refChanges)
+	if err != nil {
+		return err
+	}
+
+	identities, err := NewIdentityResolver(".", p.Config.AliasFile)
+	if err != nil {
+		fmt.Println(err)
+		return err
 	}
 
-	// Prepare maps to store committers and participants (to avoid duplicates)
-	var committers map[string]struct{} = make(map[string]struct{})
-	var committersName map[string]struct{} = make(map[string]struct{})
-	var participants map[string]struct{} = make(map[string]struct{})
-	var participantsName map[string]struct{} = make(map[string]struct{})
-
-	// Prepare a slice to store file changes
-	var fileChanges []struct {
-		FileName   template.HTML
-		Status     string
-		Committer  string
-		Reviewer   string // Assume you have this data; if not, adjust accordingly
-		CommitHash string
-		Title      string
-		Time       string
-	}
-
-	// Inside the loop where you are iterating over commitInfo, gather the necessary data:
-	for _, fileInfo := range result {
-		for _, commitInfo := range fileInfo.CommitDetails {
-
-			// Add committers and participants to the maps
-			committers[commitInfo.Email] = struct{}{}
-			committersName[commitInfo.Name] = struct{}{}
-			participants[commitInfo.Name] = struct{}{}
-			participantsName[commitInfo.CommitterName] = struct{}{}
-
-			// Add file changes to the slice
-			for _, change := range commitInfo.Changes {
-				fileChanges = append(fileChanges, struct {
-					FileName   template.HTML
-					Status     string
-					Committer  string
-					Reviewer   string
-					CommitHash string
-					Title      string
-					Time       string
-				}{
-					FileName:   template.HTML(change.FileName),
-					Status:     change.Status,
-					Committer:  commitInfo.Name, // Adjust as per your data structure
-					Reviewer:   "",              // Adjust to include reviewer information, if available
-					CommitHash: commitInfo.Hash,
-					Title:      commitInfo.Title,
-					Time:       commitInfo.AuthorTime,
-				})
+	var histories []*CommitHistory
+	// The ranges are updated in place, so that the report describes the
+	// commits actually logged.
+	for _, logOptions := range ranges {
+		logOptions.MergeDiffs = p.Config.MergeDiffs
+
+		if deepener, ok := backend.(Deepener); ok {
+			err = deepener.EnsureReachable(*logOptions, p.Config.MaxFetchDepth)
+			if errors.Is(err, ErrBaseCommitUnreachable) {
+				fmt.Printf("| \033[33m[WARNING] - %v\033[0m\n", err)
+				fmt.Println("| \033[33m[WARNING] - Falling back to a report of the current commit only. Increase max_fetch_depth or the clone depth to cover the whole range.\033[0m")
+				fmt.Println(lineBreak)
+				*logOptions = LogOptions{Old: logOptions.New, New: logOptions.New, MergeDiffs: logOptions.MergeDiffs, Strategy: RangeCommits}
+			} else if err != nil {
+				return err
 			}
 		}
-	}
 
-	// Convert maps to slices for committers and participants
-	var committersList []string
-	for committer := range committers {
-		committersList = append(committersList, committer)
+		rangeHistory, err := GetCommitInfo(backend, *logOptions, identities)
+		if err != nil {
+			fmt.Println(err)
+			return err
+		}
+		histories = append(histories, rangeHistory)
 	}
-	_ = committersList // Fix SA4010 by assigning the result of append to a variable
+	history := MergeHistories(histories...)
 
-	var committersNameList []string
-	for committerName := range committersName {
-		committersNameList = append(committersNameList, committerName)
+	fmt.Println(lineBreak)
+	fmt.Println("| \033[1;36mGit Commit Info\033[0m")
+	fmt.Println(lineBreak)
+	for _, commitInfo := range history.Commits {
+		fmt.Printf("| \033[1;36mCommit Hash:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Hash)
+		fmt.Printf("| \033[1;36mCommit Name:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Name)
+		fmt.Printf("| \033[1;36mCommit Email:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Email)
+		fmt.Printf("| \033[1;36mCommit Username:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Username)
+		fmt.Printf("| \033[1;36mCommit Author Time:\033[0m \033[1;32m%s\033[0m\n", commitInfo.AuthorTime)
+		fmt.Printf("| \033[1;36mCommit Committer Name:\033[0m \033[1;32m%s\033[0m\n", commitInfo.CommitterName)
+		fmt.Printf("| \033[1;36mCommit Committer Email:\033[0m \033[1;32m%s\033[0m\n", commitInfo.CommitterEmail)
+		fmt.Printf("| \033[1;36mCommit Ref Names:\033[0m \033[1;32m%s\033[0m\n", commitInfo.RefNames)
+		fmt.Printf("| \033[1;36mCommit Title:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Title)
+		fmt.Printf("| \033[1;36mCommit Body:\033[0m \033[1;32m%s\033[0m\n", commitInfo.Body)
+		fmt.Printf("| \033[1;36mCommit Parent Hashes:\033[0m \033[1;32m%s\033[0m\n", strings.Join(commitInfo.Parents, " "))
+		if commitInfo.IsMerge {
+			fmt.Println("| \033[1;36mMerge Commit:\033[0m \033[1;32mtrue\033[0m")
+		}
+		if len(commitInfo.CoAuthors) > 0 {
+			fmt.Printf("| \033[1;36mCo-Authors:\033[0m \033[1;32m%s\033[0m\n", strings.Join(displayNames(commitInfo.CoAuthors), ", "))
+		}
+		if len(commitInfo.ReviewedBy) > 0 {
+			fmt.Printf("| \033[1;36mReviewed By:\033[0m \033[1;32m%s\033[0m\n", strings.Join(displayNames(commitInfo.ReviewedBy), ", "))
+		}
+		if len(commitInfo.SignedOffBy) > 0 {
+			fmt.Printf("| \033[1;36mSigned Off By:\033[0m \033[1;32m%s\033[0m\n", strings.Join(displayNames(commitInfo.SignedOffBy), ", "))
+		}
+		fmt.Println("| \033[1;36mFile Changes:\033[0m")
+		for _, change := range commitInfo.Changes {
+			if from := renamedFrom(change); from != "" {
+				fmt.Printf("| \033[1;36mFile Name:\033[0m \033[1;32m%s -> %s\033[0m | \033[1;36mStatus:\033[0m \033[1;32m%s%d\033[0m | \033[1;36mLines:\033[0m \033[1;32m+%d -%d\033[0m\n", from, change.FileName, change.Status, change.Similarity, change.Additions, change.Deletions)
+				continue
+			}
+			fmt.Printf("| \033[1;36mFile Name:\033[0m \033[1;32m%s\033[0m | \033[1;36mStatus:\033[0m \033[1;32m%s\033[0m | \033[1;36mLines:\033[0m \033[1;32m+%d -%d\033[0m\n", change.FileName, change.Status, change.Additions, change.Deletions)
+		}
+		fmt.Println(lineBreak)
 	}
 
-	var participantsNameList []string
-	for participantName := range participantsName {
-		participantsNameList = append(participantsNameList, participantName)
+	mergeGroups := GroupMerges(history.Commits)
+	if len(mergeGroups) > 0 {
+		fmt.Println("| \033[1;36mMerged PRs\033[0m")
+		fmt.Println(lineBreak)
+		for _, group := range mergeGroups {
+			fmt.Printf("| \033[1;36mMerge:\033[0m \033[1;32m%s %s\033[0m | \033[1;36mCommits:\033[0m \033[1;32m%d\033[0m\n", report.ShortHash(group.Merge.Hash), group.Merge.Title, len(group.Commits))
+		}
+		fmt.Println(lineBreak)
 	}
-	_ = participantsNameList // Fix SA4010 by assigning the result of append to a variable
 
-	var participantsList []string
-	for participant := range participants {
-		participantsList = append(participantsList, participant)
+	fmt.Println("| \033[1;36mFiles Changed\033[0m")
+	fmt.Println(lineBreak)
+	for _, fileInfo := range history.Files {
+		fmt.Printf("| \033[1;36mFile Name:\033[0m \033[1;32m%s\033[0m | \033[1;36mCommits:\033[0m \033[1;32m%d\033[0m\n", fileInfo.FileName, len(fileInfo.CommitDetails))
 	}
+	fmt.Println(lineBreak)
 
-	// convert 1695494200 to Date and Time format MM/DD/YYYY HH:MM:SS AM/PM GMT e.g. 09/22/2021 12:00:00 AM -3:00
+	// Committers are deduplicated. Identities are already resolved, so each
+	// person is added once even if they committed with several emails.
+	// Co-authors are credited as committers.
+	committers := NewPeopleSet()
+	for _, commitInfo := range history.Commits {
+		committers.Add(commitInfo.Author)
+		for _, coAuthor := range commitInfo.CoAuthors {
+			committers.Add(coAuthor)
+		}
+	}
 
 	createdStr := os.Getenv("CI_BUILD_CREATED")
 
@@ -425,45 +583,64 @@ func (p *Plugin) Exec() error {
 		fmt.Println("\033[33m| No CI_BUILD_CREATED env variable found\033[0m")
 		created = time.Now().Unix()
 	}
+	buildCreated := time.Unix(created, 0)
 
-	t := time.Unix(created, 0).UTC() // Make sure it's in UTC
-
-	// add current timezone offset to the time
-	_, offsetSeconds := time.Now().Zone()
+	fmt.Println("| Current Pipeline Build Created Date/Time: " + formatBuildCreated(buildCreated))
 
-	// Convert offset in seconds to time.Duration
-	offset := time.Duration(offsetSeconds) * time.Second
-
-	t = t.Add(offset)
-
-	currentTimezone := time.Now().Format("-0700")
-	// fmt.Println(currentTimezone)
-
-	fmt.Println("| Current Pipeline Build Created Date/Time: " + t.Format("01/02/2006 03:04:05 PM "+currentTimezone))
+	var dashboard template.HTML
+	if len(dashboards) > 0 {
+		dashboard, err = htmlgenerator.GenerateDashboardSection(dashboards...)
+		if err != nil {
+			return err
+		}
 
-	createdStr = t.Format("01/02/2006 03:04:05 PM " + currentTimezone)
+		dashboardPage, err := htmlgenerator.GenerateDashboardHTML(dashboards...)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile("dashboard.html", []byte(dashboardPage), 0644)
+		if err != nil {
+			return err
+		}
+		fmt.Println("| \033[1;36mPipeline Dashboard saved to dashboard.html\033[0m")
+		fmt.Println(lineBreak)
+	}
 
 	// fmt.Println("Pipe URL: " + p.Config.PipeExecutionURL)
-	// Call the GenerateReport function
-	report, err := GenerateReport(repoName, branchName, buildType, committersNameList, committersList, pipeline.Name, p.Config.PipeExecutionURL, fileChanges, createdStr)
-	if err != nil {
-		return err
+	commitReport := &report.Report{
+		Repository: report.Repository{
+			Name:      repoName,
+			Branch:    branchName,
+			BuildType: buildType,
+		},
+		Baseline:     reportPipeline(baseline),
+		ExecutionURL: p.Config.PipeExecutionURL,
+		BuildCreated: buildCreated,
+		Range:        reportRange(ranges, refRanges),
+		Committers:   reportPeople(committers.People()),
+		Commits:      reportCommits(history.Commits, identities),
+		MergedPRs:    reportMergedPRs(mergeGroups, identities),
+		Dashboard:    dashboard,
+	}
+	renderOptions := RenderOptions{
+		MarkdownMaxSize: p.Config.MarkdownMaxSize,
+		Template:        reportTemplate,
 	}
 
 	fmt.Println(lineBreak)
 	fmt.Println("| \033[1;36mGit Commit Report\033[0m")
 	fmt.Println(lineBreak)
-	// fmt.Println(report)
-	// fmt.Println(lineBreak)
 
-	//save to a html file
-	err = os.WriteFile("report.html", []byte(report), 0644)
-	if err != nil {
+	for _, output := range outputs {
+		err = output.Write(commitReport, renderOptions)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("| \033[1;36mGit Commit Report (%s) saved to %s\033[0m\n", output.Renderer.Format(), output.Path)
+	}
+	if err := ExportOutputs(commitReport, renderOptions.HTMLTemplate()); err != nil {
 		return err
 	}
-
-	fmt.Println(lineBreak)
-	fmt.Println("| \033[1;36mGit Commit Report saved to report.html\033[0m")
 	fmt.Println(lineBreak)
 	fmt.Println("| \033[1;36mDeveloped by: \033[0m \033[1;32mDiego Pereira\033[0m")
 	fmt.Println("| \033[1;36mGithub: \033[0m \033[1;32mhttps://github.com/diegopereiraeng\033[0m")
@@ -475,17 +652,23 @@ func (p *Plugin) Exec() error {
 	return nil
 }
 
-func parsePipeline(jsonData []byte) (*models.Pipeline, error) {
+// renamedFrom returns the source path of a renamed or copied file, or an
+// empty string for every other kind of change.
+func renamedFrom(change FileChangeInfo) string {
+	if change.Status == "R" || change.Status == "C" {
+		return change.OldPath
+	}
+	return ""
+}
+
+// parsePipeline builds the pipeline model of an execution. Stages come from
+// the layout of the execution summary, their steps from the execution graph
+// when available.
+func parsePipeline(content models.Content, graph *models.ExecutionGraph) (*models.Pipeline, error) {
 	fmt.Println(lineBreak)
 	fmt.Println("| \033[1;36mParsing pipeline...\033[0m")
 	fmt.Println(lineBreak)
-
-	var response models.Response
-	err := json.Unmarshal(jsonData, &response)
-	if err != nil {
-		return nil, err
-	}
-	duration := time.Duration(response.Data.Content[0].EndTs-response.Data.Content[0].StartTs) * time.Millisecond
+	duration := time.Duration(content.EndTs-content.StartTs) * time.Millisecond
 	var durationStr string
 	if duration < time.Minute {
 		durationStr = fmt.Sprintf("%.0f seconds", duration.Seconds())
@@ -498,24 +681,39 @@ func parsePipeline(jsonData []byte) (*models.Pipeline, error) {
 	}
 
 	pipeline := models.Pipeline{
-		Name:        response.Data.Content[0].Name,
-		Status:      response.Data.Content[0].Status,
-		StartedTime: time.Unix(int64(response.Data.Content[0].StartTs/1000), 0).String(),
+		Name:        content.Name,
+		Status:      content.Status,
+		StartedTime: time.Unix(int64(content.StartTs/1000), 0).String(),
 		Duration:    durationStr,
-		StageCount:  response.Data.Content[0].TotalStagesCount,
-		StepCount:   response.Data.Content[0].SuccessfulStagesCount + response.Data.Content[0].FailedStagesCount,
+		StageCount:  content.TotalStagesCount,
+		StepCount:   content.SuccessfulStagesCount + content.FailedStagesCount,
 		Message:     "",
 	}
 
-	layoutNodeMap := response.Data.Content[0].LayoutNodeMap
+	layoutNodeMap := content.LayoutNodeMap
 
+	var stageNodes []models.NodeInfo
 	for _, nodeInfo := range layoutNodeMap {
 		if nodeInfo.NodeGroup == "STAGE" {
-			stage := models.Stage{
-				Name:   nodeInfo.Name,
-				Module: nodeInfo.Module,
-			}
+			stageNodes = append(stageNodes, nodeInfo)
+		}
+	}
+	sort.SliceStable(stageNodes, func(i, j int) bool {
+		return startedBefore(int64(stageNodes[i].StartTs), int64(stageNodes[j].StartTs))
+	})
+
+	for _, nodeInfo := range stageNodes {
+		stage := models.Stage{
+			Name:    nodeInfo.Name,
+			Status:  nodeInfo.Status,
+			Module:  nodeInfo.Module,
+			StartTs: formatTimestamp(int64(nodeInfo.StartTs)),
+			EndTs:   formatTimestamp(int64(nodeInfo.EndTs)),
+		}
 
+		if graph != nil {
+			stage.Steps = graphSteps(graph, nodeInfo.NodeIdentifier)
+		} else {
 			for _, childId := range nodeInfo.EdgeLayoutList.CurrentNodeChildren {
 				childNodeInfo := layoutNodeMap[childId]
 				step := models.Step{
@@ -524,8 +722,15 @@ func parsePipeline(jsonData []byte) (*models.Pipeline, error) {
 				}
 				stage.Steps = append(stage.Steps, step)
 			}
+		}
 
-			pipeline.Stages = append(pipeline.Stages, stage)
+		pipeline.Stages = append(pipeline.Stages, stage)
+	}
+
+	if graph != nil {
+		pipeline.StepCount = 0
+		for _, stage := range pipeline.Stages {
+			pipeline.StepCount += len(stage.Steps)
 		}
 	}
 
@@ -545,66 +750,80 @@ func parsePipeline(jsonData []byte) (*models.Pipeline, error) {
 	for _, stage := range pipeline.Stages {
 		fmt.Printf("| \033[1;36mStage Name:\033[0m \033[1;32m%s\033[0m\n", stage.Name)
 		fmt.Printf("| \033[1;36mStage Module:\033[0m \033[1;32m%s\033[0m\n", stage.Module)
-
-		// fmt.Printf("| \033[1;36mStage Step Count:\033[0m \033[1;32m%d\033[0m\n", len(stage.Steps))
+		fmt.Printf("| \033[1;36mStage Status:\033[0m \033[1;32m%s\033[0m\n", stage.Status)
+		fmt.Printf("| \033[1;36mStage Step Count:\033[0m \033[1;32m%d\033[0m\n", len(stage.Steps))
 		fmt.Println(lineBreak)
-		// fmt.Println("| \033[1;36mStage Steps\033[0m")
-		// fmt.Println(lineBreak)
-		// for _, step := range stage.Steps {
-		// 	fmt.Printf("| \033[1;36mStep Name:\033[0m \033[1;32m%s\033[0m\n", step.Name)
-		// 	fmt.Printf("| \033[1;36mStep Status:\033[0m \033[1;32m%s\033[0m\n", step.Status)
-		// 	fmt.Printf("| \033[1;36mStep Message:\033[0m \033[1;32m%s\033[0m\n", step.Message)
-		// 	fmt.Println(lineBreak)
-		// }
+		for _, step := range stage.Steps {
+			fmt.Printf("| \033[1;36mStep Name:\033[0m \033[1;32m%s\033[0m\n", step.Name)
+			fmt.Printf("| \033[1;36mStep Status:\033[0m \033[1;32m%s\033[0m\n", step.Status)
+			if step.FailureInfo.Message != "" {
+				fmt.Printf("| \033[1;36mStep Failure:\033[0m \033[1;31m%s\033[0m\n", step.FailureInfo.Message)
+			}
+			fmt.Println(lineBreak)
+		}
 	}
 
-	fmt.Println(" Json: ", pipeline)
-
 	return &pipeline, nil
 }
 
-// func Parse models.PayloadSteps(jsonData []byte) (* models.PayloadSteps, error) {
-func ParsePayloadSteps() (*models.PayloadSteps, error) {
-	fmt.Println(lineBreak)
-	fmt.Println("| \033[1;36mParsing payload...\033[0m")
-	fmt.Println(lineBreak)
+// groupingStepTypes are the execution graph nodes that only group other
+// nodes (step groups, parallel blocks, ...) and are not reported as steps.
+var groupingStepTypes = map[string]bool{
+	"NG_EXECUTION":                  true,
+	"NG_SECTION":                    true,
+	"NG_SECTION_WITH_ROLLBACK_INFO": true,
+	"NG_FORK":                       true,
+	"STEP_GROUP":                    true,
+	"STRATEGY":                      true,
+}
 
-	file, err := os.Open("pipegraph.json")
-	if err != nil {
-		fmt.Println("Error opening file:", err)
-		return nil, err
-	}
-	defer file.Close()
+// graphSteps returns the steps of a stage, identified by the fully qualified
+// name of the graph nodes (pipeline.stages.<stage>....steps.<step>), ordered
+// by start time. Steps that never started come last.
+func graphSteps(graph *models.ExecutionGraph, stageIdentifier string) []models.Step {
+	prefix := "pipeline.stages." + stageIdentifier + "."
 
-	// Read the file content
-	data, err := os.ReadFile("pipegraph.json")
-	// data, err := ioutil.ReadAll(file)
-	if err != nil {
-		fmt.Println("Error reading file:", err)
-		return nil, err
+	var nodes []models.Node
+	for _, node := range graph.NodeMap {
+		if !strings.HasPrefix(node.BaseFqn, prefix) || groupingStepTypes[node.StepType] {
+			continue
+		}
+		if !strings.Contains(strings.TrimPrefix(node.BaseFqn, prefix), "steps.") {
+			continue
+		}
+		nodes = append(nodes, node)
 	}
 
-	var payloadSteps models.PayloadSteps
-	//convert data to strings
-	// fmt.Println("Data: ", string(data))
-	// fmt.Println("Data: ", data)
-	err = json.Unmarshal(data, &payloadSteps)
-	if err != nil {
-		fmt.Println("Error unmarshalling JSON:", err)
-		return nil, err
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return startedBefore(nodes[i].StartTs, nodes[j].StartTs)
+	})
+
+	steps := make([]models.Step, 0, len(nodes))
+	for _, node := range nodes {
+		steps = append(steps, models.Step{
+			Name:        node.Name,
+			Status:      node.Status,
+			StartTs:     formatTimestamp(node.StartTs),
+			EndTs:       formatTimestamp(node.EndTs),
+			FailureInfo: node.FailureInfo,
+		})
 	}
+	return steps
+}
 
-	for _, node := range payloadSteps.Data.ExecutionGraph.NodeMap {
-		fmt.Printf("Step: %s (%s)\n", node.Name, node.Identifier)
-		fmt.Printf("Status: %s\n", node.Status)
-		if node.Status == "Failed" {
-			fmt.Printf("Failure Message: %s\n", node.FailureInfo.Message)
-			fmt.Printf("Failure Types: %v\n", node.FailureInfo.FailureTypeList)
-		}
-		fmt.Printf("Start Time: %s\n", time.Unix(0, node.StartTs*int64(time.Millisecond)).UTC())
-		fmt.Printf("End Time: %s\n", time.Unix(0, node.EndTs*int64(time.Millisecond)).UTC())
-		fmt.Println("---")
+// startedBefore orders start timestamps, with nodes that never started last.
+func startedBefore(a int64, b int64) bool {
+	if (a == 0) != (b == 0) {
+		return b == 0
 	}
+	return a < b
+}
 
-	return &payloadSteps, nil
+// formatTimestamp formats a Harness timestamp in milliseconds the way the
+// dashboard generator parses it, or returns an empty string if unset.
+func formatTimestamp(ms int64) string {
+	if ms <= 0 {
+		return ""
+	}
+	return time.UnixMilli(ms).String()
 }
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"commit-insights/internal/models"
+)
+
+// executionServer serves pages of executions from the execution summary API,
+// and their details, recording the requests.
+type executionServer struct {
+	pages      [][]models.Content
+	totalPages int
+
+	mu      sync.Mutex
+	pagesAt []int
+	sizes   []string
+	filters []ExecutionSummaryFilter
+	details []string
+}
+
+func newExecutionServer(t *testing.T, pages ...[]models.Content) (*executionServer, *HarnessClient) {
+	t.Helper()
+	s := &executionServer{pages: pages, totalPages: len(pages)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/execution/summary"):
+			var filter ExecutionSummaryFilter
+			if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+				t.Errorf("decoding the filter: %v", err)
+			}
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			s.pagesAt = append(s.pagesAt, page)
+			s.sizes = append(s.sizes, r.URL.Query().Get("size"))
+			s.filters = append(s.filters, filter)
+
+			var response models.Response
+			response.Status = "SUCCESS"
+			response.Data.TotalPages = s.totalPages
+			if page < len(s.pages) {
+				response.Data.Content = s.pages[page]
+			}
+			json.NewEncoder(w).Encode(response)
+		case r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/execution/v2/"):
+			id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			s.details = append(s.details, id)
+			w.Write([]byte(`{"status":"SUCCESS","data":{}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return s, newTestClient(t, HarnessClientConfig{BaseURL: server.URL, APIKey: "pat.secret"})
+}
+
+// execution builds an execution summary with the given commits, newest first.
+func execution(id string, status string, commits ...string) models.Content {
+	content := models.Content{PlanExecutionId: id, Name: "Build", Status: status}
+	for _, commit := range commits {
+		content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits = append(content.ModuleInfo.CI.CIExecutionInfoDTO.Branch.Commits, models.Commit{ID: commit})
+	}
+	return content
+}
+
+// executions builds count executions without commits, named prefix-0 to
+// prefix-<count-1>.
+func executions(prefix string, count int) []models.Content {
+	var page []models.Content
+	for i := 0; i < count; i++ {
+		page = append(page, execution(fmt.Sprintf("%s-%d", prefix, i), "Success"))
+	}
+	return page
+}
+
+func TestGetLastSuccessfulExecutionPaging(t *testing.T) {
+	withCommits := execution("found", "Success", "new", "middle", "old")
+
+	tests := []struct {
+		name          string
+		pages         [][]models.Content
+		totalPages    int
+		lookbackLimit int
+		// wantPages are the pages requested, in order.
+		wantPages []int
+		wantErr   string
+	}{
+		{
+			name:          "found on a later page",
+			pages:         [][]models.Content{executions("a", 10), executions("b", 10), append(executions("c", 3), withCommits)},
+			lookbackLimit: 50,
+			wantPages:     []int{0, 1, 2},
+		},
+		{
+			name:          "empty page",
+			pages:         [][]models.Content{executions("a", 10), {}, {withCommits}},
+			lookbackLimit: 50,
+			wantPages:     []int{0, 1},
+			wantErr:       "no successful execution with commits found in the last 10 executions",
+		},
+		{
+			name:          "short page",
+			pages:         [][]models.Content{executions("a", 4), {withCommits}},
+			totalPages:    5,
+			lookbackLimit: 50,
+			wantPages:     []int{0},
+			wantErr:       "in the last 4 executions",
+		},
+		{
+			name:          "last page",
+			pages:         [][]models.Content{executions("a", 10), {withCommits}},
+			totalPages:    1,
+			lookbackLimit: 50,
+			wantPages:     []int{0},
+			wantErr:       "in the last 10 executions",
+		},
+		{
+			name:          "lookback limit reached",
+			pages:         [][]models.Content{executions("a", 10), append(executions("b", 7), withCommits)},
+			lookbackLimit: 15,
+			wantPages:     []int{0, 1},
+			wantErr:       "in the last 15 executions",
+		},
+		{
+			name:          "lookback limit at a page boundary",
+			pages:         [][]models.Content{executions("a", 10), {withCommits}},
+			lookbackLimit: 10,
+			wantPages:     []int{0},
+			wantErr:       "in the last 10 executions",
+		},
+		{
+			name:          "lookback limit defaults to one execution",
+			pages:         [][]models.Content{{execution("a", "Success"), withCommits}},
+			lookbackLimit: 0,
+			wantPages:     []int{0},
+			wantErr:       "in the last 1 executions",
+		},
+		{
+			name:          "no executions",
+			pages:         [][]models.Content{{}},
+			lookbackLimit: 50,
+			wantPages:     []int{0},
+			wantErr:       "no successful execution found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newExecutionServer(t, tt.pages...)
+			if tt.totalPages > 0 {
+				server.totalPages = tt.totalPages
+			}
+
+			first, last, pipeline, err := getLastSuccessfulExecution(client, tt.lookbackLimit, "acc", "org", "proj", "pipe", nil, []string{"Success"}, "", "repo", "main", "push")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got error %v, want %q", err, tt.wantErr)
+				}
+				if len(server.details) != 0 {
+					t.Errorf("fetched the details of %v without a baseline", server.details)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if first != "new" || last != "old" || pipeline.Name != "Build" {
+					t.Errorf("got commits %s..%s of %q, want new..old of Build", first, last, pipeline.Name)
+				}
+				if !reflect.DeepEqual(server.details, []string{"found"}) {
+					t.Errorf("fetched the details of %v, want [found]", server.details)
+				}
+			}
+
+			if !reflect.DeepEqual(server.pagesAt, tt.wantPages) {
+				t.Errorf("requested pages %v, want %v", server.pagesAt, tt.wantPages)
+			}
+			for i, size := range server.sizes {
+				if size != strconv.Itoa(executionPageSize) {
+					t.Errorf("request %d: got size %s, want %d", i, size, executionPageSize)
+				}
+			}
+			for i, filter := range server.filters {
+				ci := filter.ModuleProperties.CI
+				if !reflect.DeepEqual(filter.Status, []string{"Success"}) || ci.BuildType != "branch" || ci.Branch != "main" || ci.RepoName != "repo" || filter.FilterType != "PipelineExecution" {
+					t.Errorf("request %d: got filter %+v", i, filter)
+				}
+			}
+		})
+	}
+}
+
+// withStages sets the stage nodes of an execution, as identifier, name and
+// status triples.
+func withStages(content models.Content, stages ...[3]string) models.Content {
+	content.LayoutNodeMap = models.LayoutNodeMap{}
+	for i, stage := range stages {
+		content.LayoutNodeMap[fmt.Sprintf("node-%d", i)] = models.NodeInfo{NodeGroup: "STAGE", NodeIdentifier: stage[0], Name: stage[1], Status: stage[2]}
+	}
+	// Steps are not stages, whatever their identifier.
+	content.LayoutNodeMap["step"] = models.NodeInfo{NodeGroup: "STEP", NodeIdentifier: "Deploy", Name: "Deploy", Status: "Success"}
+	return content
+}
+
+func TestUnmetStage(t *testing.T) {
+	layout := withStages(models.Content{},
+		[3]string{"Build_Golang", "Build Golang", "Success"},
+		[3]string{"Deploy_Prod", "Deploy Prod", "Failed"},
+	).LayoutNodeMap
+	statuses := []string{"success", "IgnoreFailed"}
+
+	tests := []struct {
+		stageIDs []string
+		want     string
+	}{
+		{nil, ""},
+		{[]string{"Build_Golang"}, ""},
+		{[]string{"Build Golang"}, ""},
+		{[]string{"Build_Golang", "Deploy_Prod"}, "Stage Deploy_Prod finished with status Failed"},
+		{[]string{"Deploy Prod"}, "Stage Deploy Prod finished with status Failed"},
+		{[]string{"Test"}, "Stage Test did not run in this execution"},
+		{[]string{"Deploy"}, "Stage Deploy did not run in this execution"},
+	}
+	for _, tt := range tests {
+		if got := unmetStage(layout, tt.stageIDs, statuses); got != tt.want {
+			t.Errorf("unmetStage(%v) = %q, want %q", tt.stageIDs, got, tt.want)
+		}
+	}
+}
+
+func TestGetLastSuccessfulExecutionStages(t *testing.T) {
+	build := [3]string{"Build_Golang", "Build Golang", "Success"}
+	failedDeploy := [3]string{"Deploy_Prod", "Deploy Prod", "Failed"}
+	deploy := [3]string{"Deploy_Prod", "Deploy Prod", "Success"}
+
+	page := []models.Content{
+		// The running execution qualifies, but is never the baseline.
+		withStages(execution("current", "Running", "current-new", "current-old"), build, deploy),
+		withStages(execution("no-deploy", "Success", "a-new", "a-old"), build),
+		withStages(execution("failed-deploy", "Failed", "b-new", "b-old"), build, failedDeploy),
+		withStages(execution("failed-pipeline", "Failed", "c-new", "c-old"), build, deploy),
+		withStages(execution("older", "Success", "d-new", "d-old"), build, deploy),
+	}
+
+	tests := []struct {
+		name     string
+		stageIDs []string
+		want     string
+	}{
+		{name: "by identifier", stageIDs: []string{"Build_Golang"}, want: "a-new"},
+		{name: "by name", stageIDs: []string{"Build Golang"}, want: "a-new"},
+		{name: "missing stage", stageIDs: []string{"Build_Golang", "Deploy_Prod"}, want: "c-new"},
+		{name: "missing stage by name", stageIDs: []string{"Deploy Prod"}, want: "c-new"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, client := newExecutionServer(t, page)
+			first, _, _, err := getLastSuccessfulExecution(client, 50, "acc", "org", "proj", "pipe", tt.stageIDs, []string{"Success"}, "current", "repo", "main", "pull_request")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if first != tt.want {
+				t.Errorf("got baseline commit %s, want %s", first, tt.want)
+			}
+			// The execution status is not filtered, the stages are.
+			if filter := server.filters[0]; filter.Status != nil || filter.ModuleProperties.CI.BuildType != "PR" {
+				t.Errorf("got filter %+v, want no status and the PR build type", filter)
+			}
+		})
+	}
+
+	_, client := newExecutionServer(t, page)
+	_, _, _, err := getLastSuccessfulExecution(client, 50, "acc", "org", "proj", "pipe", []string{"Test"}, []string{"Success"}, "current", "repo", "main", "push")
+	if err == nil || !strings.Contains(err.Error(), "in the last 5 executions") {
+		t.Errorf("got error %v for a stage that never ran", err)
+	}
+}
+
+func TestGraphSteps(t *testing.T) {
+	node := func(fqn string, stepType string, name string, start int64) models.Node {
+		return models.Node{Uuid: fqn, Name: name, Identifier: name, BaseFqn: fqn, StepType: stepType, StartTs: start, EndTs: start + 1000, Status: "Success"}
+	}
+
+	// A stage with step groups, a parallel block and a looping strategy, the
+	// nodes listed out of order like in the API's node map.
+	nodes := []models.Node{
+		node("pipeline.stages.build", "CI", "build", 1000),
+		node("pipeline.stages.build.spec.execution", "NG_EXECUTION", "execution", 1000),
+		node("pipeline.stages.build.spec.execution.steps.parallel", "NG_FORK", "parallel", 3000),
+		node("pipeline.stages.build.spec.execution.steps.lint", "Run", "lint", 3000),
+		node("pipeline.stages.build.spec.execution.steps.unit", "Run", "unit", 3500),
+		node("pipeline.stages.build.spec.execution.steps.checkout", "Run", "checkout", 2000),
+		node("pipeline.stages.build.spec.execution.steps.group", "STEP_GROUP", "group", 4000),
+		node("pipeline.stages.build.spec.execution.steps.group.steps.matrix", "STRATEGY", "matrix", 4000),
+		node("pipeline.stages.build.spec.execution.steps.group.steps.matrix.steps.compile_0", "Run", "compile_0", 4100),
+		node("pipeline.stages.build.spec.execution.steps.group.steps.matrix.steps.compile_1", "Run", "compile_1", 4050),
+		node("pipeline.stages.build.spec.execution.steps.publish", "Run", "publish", 0),
+		// Another stage, whose identifier starts like the first one.
+		node("pipeline.stages.build_docs.spec.execution.steps.docs", "Run", "docs", 2500),
+		// Stage level nodes that are not steps.
+		node("pipeline.stages.build.spec.infrastructure", "Infrastructure", "infrastructure", 500),
+	}
+	graph := &models.ExecutionGraph{NodeMap: map[string]models.Node{}}
+	for _, n := range nodes {
+		graph.NodeMap[n.Uuid] = n
+	}
+	failed := graph.NodeMap["pipeline.stages.build.spec.execution.steps.unit"]
+	failed.Status = "Failed"
+	failed.FailureInfo = models.FailureInfo{Message: "exit status 1"}
+	graph.NodeMap[failed.Uuid] = failed
+
+	steps := graphSteps(graph, "build")
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+	// Ordered by start time, the step that never started last.
+	want := []string{"checkout", "lint", "unit", "compile_1", "compile_0", "publish"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("got steps %v, want %v", names, want)
+	}
+
+	unit := steps[2]
+	if unit.Status != "Failed" || unit.FailureInfo.Message != "exit status 1" {
+		t.Errorf("got unit step %+v, want the failure", unit)
+	}
+	if unit.StartTs != formatTimestamp(3500) || unit.EndTs != formatTimestamp(4500) {
+		t.Errorf("got unit step times %q to %q", unit.StartTs, unit.EndTs)
+	}
+	if publish := steps[5]; publish.StartTs != "" {
+		t.Errorf("got start time %q for a step that never started", publish.StartTs)
+	}
+
+	if docs := graphSteps(graph, "build_docs"); len(docs) != 1 || docs[0].Name != "docs" {
+		t.Errorf("got steps %+v for build_docs, want docs", docs)
+	}
+	if missing := graphSteps(graph, "deploy"); len(missing) != 0 {
+		t.Errorf("got steps %+v for a stage without nodes", missing)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"commit-insights/internal/payloads"
+)
+
+// Supported values for the range_strategy setting.
+const (
+	// RangeAuto uses RangeMergeBase for pull requests and RangeCommits for
+	// every other build.
+	RangeAuto = "auto"
+	// RangeCommits reports every commit between the baseline and the current
+	// commit (old^..new).
+	RangeCommits = "range"
+	// RangeMergeBase reports only the commits of the current branch that are
+	// not in the target branch (merge-base..new).
+	RangeMergeBase = "merge-base"
+	// RangeFirstParent is RangeCommits following only the first parent of
+	// merge commits, i.e. the history as seen from the branch itself.
+	RangeFirstParent = "first-parent"
+)
+
+// ErrUnknownRangeStrategy is returned by ResolveRange for invalid strategies.
+var ErrUnknownRangeStrategy = errors.New("unknown range strategy")
+
+// isPullRequest reports whether the build type is a pull request build, in
+// either the Drone (pull_request) or the Harness (PR) spelling.
+func isPullRequest(buildType string) bool {
+	return buildType == "pull_request" || buildType == "PR"
+}
+
+// ResolveRange turns the baseline and current commits into the range to log
+// according to the strategy. targetBranch is only used for merge-base ranges.
+func ResolveRange(backend GitBackend, strategy string, buildType string, targetBranch string, olderCommitHash string, newerCommitHash string) (LogOptions, error) {
+	opts := LogOptions{
+		Old: strings.TrimSpace(olderCommitHash),
+		New: strings.TrimSpace(newerCommitHash),
+	}
+
+	strategy = strings.ToLower(strings.TrimSpace(strategy))
+	if strategy == "" || strategy == RangeAuto {
+		strategy = RangeCommits
+		if isPullRequest(buildType) {
+			strategy = RangeMergeBase
+		}
+	}
+
+	fmt.Printf("| \033[1;36mRange Strategy:\033[0m \033[1;32m%s\033[0m\n", strategy)
+	opts.Strategy = strategy
+
+	switch strategy {
+	case RangeCommits:
+		return opts, nil
+	case RangeFirstParent:
+		opts.FirstParent = true
+		return opts, nil
+	case RangeMergeBase:
+		if targetBranch == "" {
+			return opts, fmt.Errorf("the %s range strategy needs a target branch", RangeMergeBase)
+		}
+		target, err := resolveTargetBranch(backend, targetBranch)
+		if err != nil {
+			return opts, err
+		}
+		mergeBase, err := backend.MergeBase(target, opts.New)
+		if err != nil {
+			return opts, fmt.Errorf("finding the merge base of %s and %s: %w", target, opts.New, err)
+		}
+		fmt.Printf("| \033[1;36mMerge Base with %s:\033[0m \033[1;32m%s\033[0m\n", target, mergeBase)
+		opts.Old = mergeBase
+		opts.ExcludeOld = true
+		return opts, nil
+	default:
+		return opts, fmt.Errorf("%w %q, expected one of: %s, %s, %s, %s", ErrUnknownRangeStrategy, strategy, RangeAuto, RangeCommits, RangeMergeBase, RangeFirstParent)
+	}
+}
+
+// resolveTargetBranch finds the target branch in the local clone, preferring
+// the remote-tracking branch. PR clones usually only contain the source
+// branch, so the target is fetched when the backend supports it.
+func resolveTargetBranch(backend GitBackend, branch string) (string, error) {
+	candidates := []string{"origin/" + branch, branch}
+	for _, candidate := range candidates {
+		if _, err := backend.ResolveRevision(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+
+	if fetcher, ok := backend.(BranchFetcher); ok {
+		fmt.Printf("| \033[33mTarget branch %s not found locally, fetching it...\033[0m\n", branch)
+		if err := fetcher.FetchBranch(branch); err == nil {
+			if _, err := backend.ResolveRevision(candidates[0]); err == nil {
+				return candidates[0], nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("target branch %q not found in the repository", branch)
+}
+
+// RefRange is the commit range of a ref updated by a push.
+type RefRange struct {
+	Change payloads.RefChange
+	// Options is nil when the ref has no range, i.e. it was deleted.
+	Options *LogOptions
+}
+
+// ResolveRefRanges resolves the range of every ref updated by a push:
+//   - updated branches use the strategy, like any other push build, but
+//     exclude the old commit: the push only added the commits of old..new
+//   - created branches start at their merge base with defaultBranch
+//   - deleted refs have no range, they are only reported
+//   - tags are releases, covering the tagged commit (or old..new when an
+//     existing tag was moved)
+func ResolveRefRanges(backend GitBackend, strategy string, targetBranch string, defaultBranch string, changes []payloads.RefChange) ([]RefRange, error) {
+	var ranges []RefRange
+	for _, change := range changes {
+		fmt.Printf("| \033[1;36mRef:\033[0m \033[1;32m%s (%s %s)\033[0m\n", change.Name, change.Type, change.Action())
+
+		refRange := RefRange{Change: change}
+		single := LogOptions{Old: change.NewSHA, New: change.NewSHA, Strategy: RangeCommits}
+
+		switch {
+		case change.Action() == payloads.ActionDeleted:
+			// Nothing to log.
+		case change.Action() == payloads.ActionCreated && change.Type == payloads.RefBranch && defaultBranch != "" && defaultBranch != change.Name:
+			opts, err := ResolveRange(backend, RangeMergeBase, "push", defaultBranch, change.NewSHA, change.NewSHA)
+			if err != nil {
+				fmt.Printf("| \033[33m[WARNING] - %v, reporting the branch head only\033[0m\n", err)
+				opts = single
+			}
+			refRange.Options = &opts
+		case change.Action() == payloads.ActionCreated:
+			refRange.Options = &single
+		default:
+			opts, err := ResolveRange(backend, strategy, "push", targetBranch, change.OldSHA, change.NewSHA)
+			if errors.Is(err, ErrUnknownRangeStrategy) {
+				return nil, err
+			} else if err != nil {
+				fmt.Printf("| \033[33m[WARNING] - %v, falling back to the pushed range\033[0m\n", err)
+				opts = LogOptions{Old: change.OldSHA, New: change.NewSHA, Strategy: RangeCommits}
+			}
+			// The old commit was already pushed before.
+			opts.ExcludeOld = true
+			refRange.Options = &opts
+		}
+
+		ranges = append(ranges, refRange)
+	}
+	return ranges, nil
+}
+
+// resolveRanges resolves the ranges to log: one per ref updated by a push
+// payload, else the range between the baseline and the current commit, which
+// falls back to the baseline range when the strategy cannot be applied. The
+// ranges point into the ref ranges, so both see the changes made when logging.
+func resolveRanges(backend GitBackend, strategy string, buildType string, targetBranch string, defaultBranch string, olderCommitHash string, newerCommitHash string, changes []payloads.RefChange) ([]*LogOptions, []RefRange, error) {
+	if len(changes) == 0 {
+		opts, err := ResolveRange(backend, strategy, buildType, targetBranch, olderCommitHash, newerCommitHash)
+		if errors.Is(err, ErrUnknownRangeStrategy) {
+			return nil, nil, err
+		} else if err != nil {
+			fmt.Printf("| \033[33m[WARNING] - %v\033[0m\n", err)
+			fmt.Println("| \033[33m[WARNING] - Falling back to the baseline commit range.\033[0m")
+			fmt.Println(lineBreak)
+			opts = LogOptions{Old: strings.TrimSpace(olderCommitHash), New: strings.TrimSpace(newerCommitHash), Strategy: RangeCommits}
+		}
+		return []*LogOptions{&opts}, nil, nil
+	}
+
+	if defaultBranch == "" {
+		defaultBranch = targetBranch
+	}
+	refRanges, err := ResolveRefRanges(backend, strategy, targetBranch, defaultBranch, changes)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ranges []*LogOptions
+	for _, refRange := range refRanges {
+		if refRange.Options == nil {
+			fmt.Printf("| \033[33m%s %s was deleted, no commits to report\033[0m\n", refRange.Change.Type, refRange.Change.Name)
+			continue
+		}
+		ranges = append(ranges, refRange.Options)
+	}
+	return ranges, refRanges, nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"commit-insights/internal/payloads"
+	"commit-insights/internal/report"
+)
+
+func TestResolveRefRanges(t *testing.T) {
+	r := newMemoryRepo(t)
+	r.write("main.go", "package main\n")
+	root := r.commit("Initial commit")
+	r.write("main.go", "package main\n\nfunc main() {}\n")
+	second := r.commit("Add main")
+	r.checkout("feature", true)
+	r.write("feature.go", "package main\n")
+	feature := r.commit("Add feature")
+	r.checkout("master", false)
+	r.write("README.md", "# Insights\n")
+	third := r.commit("Add README")
+
+	backend := NewGoGitBackend(r.repo)
+
+	tests := []struct {
+		name     string
+		strategy string
+		change   payloads.RefChange
+		want     *LogOptions
+		commits  []string
+	}{
+		{
+			name:    "updated branch",
+			change:  payloads.RefChange{Name: "master", Type: payloads.RefBranch, OldSHA: root, NewSHA: third},
+			want:    &LogOptions{Old: root, New: third, ExcludeOld: true, Strategy: RangeCommits},
+			commits: []string{third, second},
+		},
+		{
+			name:     "updated branch with first-parent",
+			strategy: RangeFirstParent,
+			change:   payloads.RefChange{Name: "master", Type: payloads.RefBranch, OldSHA: second, NewSHA: third},
+			want:     &LogOptions{Old: second, New: third, ExcludeOld: true, FirstParent: true, Strategy: RangeFirstParent},
+			commits:  []string{third},
+		},
+		{
+			name:     "updated branch without target branch",
+			strategy: RangeMergeBase,
+			change:   payloads.RefChange{Name: "master", Type: payloads.RefBranch, OldSHA: second, NewSHA: third},
+			want:     &LogOptions{Old: second, New: third, ExcludeOld: true, Strategy: RangeCommits},
+			commits:  []string{third},
+		},
+		{
+			name:    "moved tag",
+			change:  payloads.RefChange{Name: "v1.0.0", Type: payloads.RefTag, OldSHA: root, NewSHA: second},
+			want:    &LogOptions{Old: root, New: second, ExcludeOld: true, Strategy: RangeCommits},
+			commits: []string{second},
+		},
+		{
+			name:    "created branch",
+			change:  payloads.RefChange{Name: "feature", Type: payloads.RefBranch, NewSHA: feature},
+			want:    &LogOptions{Old: second, New: feature, ExcludeOld: true, Strategy: RangeMergeBase},
+			commits: []string{feature},
+		},
+		{
+			name:    "created tag",
+			change:  payloads.RefChange{Name: "v2.0.0", Type: payloads.RefTag, NewSHA: third},
+			want:    &LogOptions{Old: third, New: third, Strategy: RangeCommits},
+			commits: []string{third},
+		},
+		{
+			name:   "deleted branch",
+			change: payloads.RefChange{Name: "feature", Type: payloads.RefBranch, OldSHA: feature},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges, err := ResolveRefRanges(backend, tt.strategy, "", "master", []payloads.RefChange{tt.change})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ranges) != 1 {
+				t.Fatalf("got %d ranges, want 1", len(ranges))
+			}
+			if got := ranges[0].Options; !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got options %+v, want %+v", got, tt.want)
+			}
+			if tt.want == nil {
+				return
+			}
+
+			commits, err := backend.Log(*tt.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hashes(commits); !reflect.DeepEqual(got, tt.commits) {
+				t.Errorf("got commits %v, want %v", got, tt.commits)
+			}
+		})
+	}
+
+	change := payloads.RefChange{Name: "master", Type: payloads.RefBranch, OldSHA: root, NewSHA: third}
+	if _, err := ResolveRefRanges(backend, "everything", "", "master", []payloads.RefChange{change}); !errors.Is(err, ErrUnknownRangeStrategy) {
+		t.Errorf("got error %v, want ErrUnknownRangeStrategy", err)
+	}
+}
+
+func TestReportRange(t *testing.T) {
+	r := newMemoryRepo(t)
+	r.write("main.go", "package main\n")
+	root := r.commit("Initial commit")
+	r.write("main.go", "package main\n\nfunc main() {}\n")
+	second := r.commit("Add main")
+	r.checkout("feature", true)
+	r.write("feature.go", "package main\n")
+	feature := r.commit("Add feature")
+	r.checkout("master", false)
+	r.write("README.md", "# Insights\n")
+	third := r.commit("Add README")
+
+	backend := NewGoGitBackend(r.repo)
+
+	tests := []struct {
+		name         string
+		strategy     string
+		buildType    string
+		targetBranch string
+		old, new     string
+		changes      []payloads.RefChange
+		want         report.Range
+		commits      []string
+	}{
+		{
+			// PR builds have no baseline, old and new are both the head of
+			// the pull request.
+			name:         "pull request",
+			strategy:     RangeAuto,
+			buildType:    "pull_request",
+			targetBranch: "master",
+			old:          feature,
+			new:          feature,
+			want:         report.Range{LogRange: report.LogRange{Strategy: RangeMergeBase, Old: second, New: feature, ExcludeOld: true}},
+			commits:      []string{feature},
+		},
+		{
+			name:         "pull request with a missing target branch",
+			strategy:     RangeAuto,
+			buildType:    "PR",
+			targetBranch: "release",
+			old:          feature,
+			new:          feature,
+			want:         report.Range{LogRange: report.LogRange{Strategy: RangeCommits, Old: feature, New: feature}},
+			commits:      []string{feature},
+		},
+		{
+			name:      "push",
+			buildType: "push",
+			old:       second,
+			new:       third,
+			want:      report.Range{LogRange: report.LogRange{Strategy: RangeCommits, Old: second, New: third}},
+			commits:   []string{third, second},
+		},
+		{
+			name:      "push payload",
+			strategy:  RangeAuto,
+			buildType: "push",
+			changes: []payloads.RefChange{
+				{Ref: "refs/heads/feature", Name: "feature", Type: payloads.RefBranch, OldSHA: feature},
+				{Ref: "refs/heads/master", Name: "master", Type: payloads.RefBranch, OldSHA: root, NewSHA: third},
+			},
+			want: report.Range{
+				LogRange: report.LogRange{Strategy: RangeCommits, Old: root, New: third, ExcludeOld: true},
+				Refs: []report.RefUpdate{
+					{Ref: "refs/heads/feature", Name: "feature", Type: "branch", Action: "deleted", OldSHA: feature},
+					{Ref: "refs/heads/master", Name: "master", Type: "branch", Action: "updated", OldSHA: root, NewSHA: third, Range: &report.LogRange{Strategy: RangeCommits, Old: root, New: third, ExcludeOld: true}},
+				},
+			},
+			commits: []string{third, second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranges, refRanges, err := resolveRanges(backend, tt.strategy, tt.buildType, tt.targetBranch, "", tt.old, tt.new, tt.changes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := reportRange(ranges, refRanges); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got range %+v, want %+v", got, tt.want)
+			}
+
+			commits, err := backend.Log(*ranges[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hashes(commits); !reflect.DeepEqual(got, tt.commits) {
+				t.Errorf("got commits %v, want %v", got, tt.commits)
+			}
+		})
+	}
+}
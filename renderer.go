@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"commit-insights/internal/report"
+)
+
+// RenderOptions configure the renderers.
+type RenderOptions struct {
+	// MarkdownMaxSize is the size limit of the Markdown report, 0 disables it.
+	MarkdownMaxSize int
+	// Template is the user-supplied template of the html or text format, nil
+	// for the built-in ones.
+	Template *ReportTemplate
+}
+
+// HTMLTemplate returns the template of the HTML report, nil for the built-in
+// one.
+func (opts RenderOptions) HTMLTemplate() *ReportTemplate {
+	if opts.Template != nil && opts.Template.IsHTML() {
+		return opts.Template
+	}
+	return nil
+}
+
+// Renderer renders a report in one output format.
+type Renderer interface {
+	// Format is the name of the format in the format setting.
+	Format() string
+	// DefaultPath is the file the report is written to when the format
+	// setting gives no path.
+	DefaultPath() string
+	Render(r *report.Report, opts RenderOptions) ([]byte, error)
+}
+
+// ErrUnknownFormat is returned for formats without a registered renderer.
+var ErrUnknownFormat = errors.New("unknown output format")
+
+// DefaultFormats are the formats written when the format setting is empty.
+var DefaultFormats = []string{"html", "json"}
+
+// renderers are the registered renderers, in the order they are listed.
+var renderers = []Renderer{
+	htmlRenderer{},
+	jsonRenderer{},
+	markdownRenderer{},
+	csvRenderer{},
+	textRenderer{},
+}
+
+// RegisterRenderer adds a renderer, replacing the one registered for the same
+// format if any.
+func RegisterRenderer(renderer Renderer) {
+	for i, r := range renderers {
+		if r.Format() == renderer.Format() {
+			renderers[i] = renderer
+			return
+		}
+	}
+	renderers = append(renderers, renderer)
+}
+
+// Formats returns the formats of the registered renderers.
+func Formats() []string {
+	formats := make([]string, 0, len(renderers))
+	for _, r := range renderers {
+		formats = append(formats, r.Format())
+	}
+	return formats
+}
+
+// Output is a report format to write and the file to write it to.
+type Output struct {
+	Renderer Renderer
+	Path     string
+}
+
+// ParseOutputs parses the values of the format setting, each a format
+// optionally followed by the path of its file: "markdown" or
+// "markdown=summary.md". A format listed twice is written to both paths.
+func ParseOutputs(values []string) ([]Output, error) {
+	if len(values) == 0 {
+		values = DefaultFormats
+	}
+
+	var outputs []Output
+	for _, value := range values {
+		format, path, _ := strings.Cut(strings.TrimSpace(value), "=")
+		format = strings.ToLower(strings.TrimSpace(format))
+		if format == "" {
+			continue
+		}
+
+		var renderer Renderer
+		for _, r := range renderers {
+			if r.Format() == format {
+				renderer = r
+				break
+			}
+		}
+		if renderer == nil {
+			return nil, fmt.Errorf("%w %q, expected one of: %s", ErrUnknownFormat, format, strings.Join(Formats(), ", "))
+		}
+
+		path = strings.TrimSpace(path)
+		if path == "" {
+			path = renderer.DefaultPath()
+		}
+		outputs = append(outputs, Output{Renderer: renderer, Path: path})
+	}
+	return outputs, nil
+}
+
+// Write renders the report and writes it to the output's path, creating its
+// directory when needed.
+func (o Output) Write(r *report.Report, opts RenderOptions) error {
+	data, err := o.Renderer.Render(r, opts)
+	if err != nil {
+		return fmt.Errorf("rendering %s report: %w", o.Renderer.Format(), err)
+	}
+	if dir := filepath.Dir(o.Path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(o.Path, data, 0644)
+}
+
+// htmlRenderer renders the HTML report.
+type htmlRenderer struct{}
+
+func (htmlRenderer) Format() string      { return "html" }
+func (htmlRenderer) DefaultPath() string { return "report.html" }
+
+func (htmlRenderer) Render(r *report.Report, opts RenderOptions) ([]byte, error) {
+	html, err := GenerateReport(r, opts.HTMLTemplate())
+	return []byte(html), err
+}
+
+type jsonRenderer struct{}
+
+func (jsonRenderer) Format() string      { return "json" }
+func (jsonRenderer) DefaultPath() string { return "insights.json" }
+
+func (jsonRenderer) Render(r *report.Report, opts RenderOptions) ([]byte, error) {
+	data, err := json.MarshalIndent(BuildInsights(r), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+type markdownRenderer struct{}
+
+func (markdownRenderer) Format() string      { return "markdown" }
+func (markdownRenderer) DefaultPath() string { return "report.md" }
+
+func (markdownRenderer) Render(r *report.Report, opts RenderOptions) ([]byte, error) {
+	return []byte(RenderMarkdown(r, opts.MarkdownMaxSize)), nil
+}
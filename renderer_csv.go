@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+
+	"commit-insights/internal/report"
+)
+
+// csvHeader lists the columns of the CSV report, one row per file change of
+// each commit. Commits without file changes, e.g. merges, have a single row
+// with empty file columns.
+var csvHeader = []string{
+	"commit", "author", "author_email", "time", "title", "merge",
+	"status", "path", "old_path", "additions", "deletions", "binary",
+}
+
+// csvRenderer renders the file changes as CSV, for spreadsheets and data
+// warehouses.
+type csvRenderer struct{}
+
+func (csvRenderer) Format() string      { return "csv" }
+func (csvRenderer) DefaultPath() string { return "report.csv" }
+
+func (csvRenderer) Render(r *report.Report, opts RenderOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+	for _, commit := range r.Commits {
+		row := []string{
+			commit.Hash,
+			commit.Author.Name,
+			commit.Author.Email,
+			insightsTime(commit.Time),
+			commit.Title,
+			strconv.FormatBool(commit.Merge),
+		}
+		if len(commit.Changes) == 0 {
+			if err := w.Write(append(row, "", "", "", "", "", "")); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, change := range commit.Changes {
+			fileRow := append(row[:len(row):len(row)],
+				change.Status,
+				change.Path,
+				change.OldPath,
+				strconv.Itoa(change.Additions),
+				strconv.Itoa(change.Deletions),
+				strconv.FormatBool(change.Binary),
+			)
+			if err := w.Write(fileRow); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
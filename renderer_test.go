@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"commit-insights/internal/report"
+)
+
+func TestParseOutputs(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{name: "default", want: []string{"html=report.html", "json=insights.json"}},
+		{name: "paths", values: []string{"markdown=summary.md", " CSV ", "text = out/report.txt"}, want: []string{"markdown=summary.md", "csv=report.csv", "text=out/report.txt"}},
+		{name: "format listed twice", values: []string{"json", "json=copy.json"}, want: []string{"json=insights.json", "json=copy.json"}},
+		{name: "empty values", values: []string{"", " "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputs, err := ParseOutputs(tt.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, output := range outputs {
+				got = append(got, output.Renderer.Format()+"="+output.Path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got outputs %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseOutputs([]string{"pdf"}); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("got error %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestRenderTextMerges(t *testing.T) {
+	merge := report.CommitEntry{Hash: "6113728f27ae82c7b1a177c8d03f9e96e0adf246", Title: "Merge feature", Merge: true}
+	commit := report.CommitEntry{Hash: "9049f1265b7d61be4a8904a9a27120d2064dab3b", Title: "Add feature"}
+
+	tests := []struct {
+		commits []report.CommitEntry
+		want    string
+	}{
+		{commits: []report.CommitEntry{commit}, want: "Commits:    1 (0 merges)\n"},
+		{commits: []report.CommitEntry{merge, commit}, want: "Commits:    2 (1 merge)\n"},
+		{commits: []report.CommitEntry{merge, merge, commit}, want: "Commits:    3 (2 merges)\n"},
+	}
+
+	for _, tt := range tests {
+		r := &report.Report{Repository: report.Repository{Name: "commit-insights"}, Commits: tt.commits}
+		text, err := textRenderer{}.Render(r, RenderOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(text), tt.want) {
+			t.Errorf("report does not contain %q:\n%s", tt.want, text)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"commit-insights/internal/report"
+)
+
+// textRenderer renders a plain text report, for logs and emails, or the
+// user-supplied text template.
+type textRenderer struct{}
+
+func (textRenderer) Format() string      { return "text" }
+func (textRenderer) DefaultPath() string { return "report.txt" }
+
+func (textRenderer) Render(r *report.Report, opts RenderOptions) ([]byte, error) {
+	if opts.Template != nil && !opts.Template.IsHTML() {
+		var b strings.Builder
+		err := opts.Template.Execute(&b, newReportData(r))
+		if err == nil {
+			return []byte(b.String()), nil
+		}
+		fmt.Printf("| \033[33m[WARNING] - Failed to render the template %s, using the built-in text report: %v\033[0m\n", opts.Template.Path, err)
+	}
+
+	var b strings.Builder
+
+	title := "Commit Insights: " + r.Repository.Name
+	if r.Repository.Branch != "" {
+		title += " (" + r.Repository.Branch + ")"
+	}
+	b.WriteString(title + "\n")
+	b.WriteString(strings.Repeat("=", len(title)) + "\n\n")
+
+	if r.Repository.BuildType != "" {
+		fmt.Fprintf(&b, "Build:      %s\n", r.Repository.BuildType)
+	}
+	if pipeline := r.Baseline; pipeline != nil {
+		fmt.Fprintf(&b, "Baseline:   %s (%s)\n", pipeline.Name, pipeline.Status)
+	}
+	if r.ExecutionURL != "" {
+		fmt.Fprintf(&b, "Execution:  %s\n", r.ExecutionURL)
+	}
+	if r.Range.Old != "" || r.Range.New != "" {
+		fmt.Fprintf(&b, "Range:      %s..%s\n", report.ShortHash(r.Range.Old), report.ShortHash(r.Range.New))
+	}
+	stats := r.Stats()
+	fmt.Fprintf(&b, "Commits:    %d (%s)\n", stats.Commits, plural(stats.MergeCommits, "merge"))
+	fmt.Fprintf(&b, "Files:      %d (%d binary)\n", stats.FilesChanged, stats.BinaryFiles)
+	fmt.Fprintf(&b, "Lines:      +%d -%d\n", stats.Additions, stats.Deletions)
+
+	if len(r.Range.Refs) > 0 {
+		b.WriteString("\nRefs\n----\n")
+		for _, ref := range r.Range.Refs {
+			line := fmt.Sprintf("%s %s %s", ref.Type, ref.Name, ref.Action)
+			if ref.Release {
+				line += " (release)"
+			}
+			b.WriteString("  " + line + "\n")
+		}
+	}
+
+	if authors := r.Authors(); len(authors) > 0 {
+		b.WriteString("\nCommitters\n----------\n")
+		for _, author := range authors {
+			name := author.Name
+			if author.Email != "" {
+				name += " <" + author.Email + ">"
+			}
+			fmt.Fprintf(&b, "  %s: %s, +%d -%d\n", name, plural(author.Commits, "commit"), author.Additions, author.Deletions)
+		}
+	}
+
+	if files := r.Files(); len(files) > 0 {
+		b.WriteString("\nFiles Changed\n-------------\n")
+		for _, file := range files {
+			lines := fmt.Sprintf("+%d -%d", file.Additions, file.Deletions)
+			if file.Binary {
+				lines = "binary"
+			}
+			fmt.Fprintf(&b, "  %s %s (%s, %s)\n", file.Status, file.Path, lines, plural(len(file.Commits), "commit"))
+		}
+	}
+
+	if len(r.Commits) > 0 {
+		b.WriteString("\nCommits\n-------\n")
+		for _, commit := range r.Commits {
+			fmt.Fprintf(&b, "  %s %s (%s, %s)\n", commit.ShortHash(), commit.Title, commit.Author.Name, insightsTime(commit.Time))
+			for _, change := range commit.Changes {
+				path := change.Path
+				if change.OldPath != "" {
+					path = change.OldPath + " -> " + path
+				}
+				fmt.Fprintf(&b, "      %s %s\n", change.Status, path)
+			}
+		}
+	}
+
+	return []byte(b.String()), nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"commit-insights/internal/models"
+	"commit-insights/internal/report"
+)
+
+// The functions below convert the commit history, pipeline and payload refs
+// to the report model shared by the renderers.
+
+// reportCommits converts commits to report entries. Committers are resolved
+// like authors, which already are.
+func reportCommits(commits []CommitInfo, identities *IdentityResolver) []report.CommitEntry {
+	entries := make([]report.CommitEntry, 0, len(commits))
+	for _, commit := range commits {
+		entries = append(entries, reportCommit(commit, identities))
+	}
+	return entries
+}
+
+func reportCommit(commit CommitInfo, identities *IdentityResolver) report.CommitEntry {
+	entry := report.CommitEntry{
+		Hash:        commit.Hash,
+		Title:       commit.Title,
+		Body:        commit.Body,
+		Author:      report.Person{Name: commit.Author.Name, Email: commit.Author.Email, Username: commit.Username},
+		Committer:   reportPerson(identities.Resolve(Person{Name: commit.CommitterName, Email: commit.CommitterEmail})),
+		Parents:     commit.Parents,
+		Merge:       commit.IsMerge,
+		CoAuthors:   reportPeople(commit.CoAuthors),
+		ReviewedBy:  reportPeople(commit.ReviewedBy),
+		SignedOffBy: reportPeople(commit.SignedOffBy),
+		Additions:   commit.Additions,
+		Deletions:   commit.Deletions,
+	}
+
+	if seconds, err := strconv.ParseInt(commit.AuthorTime, 10, 64); err == nil {
+		entry.Time = time.Unix(seconds, 0)
+	}
+
+	for _, change := range commit.Changes {
+		entry.Changes = append(entry.Changes, report.FileChange{
+			Path:       change.FileName,
+			OldPath:    renamedFrom(change),
+			Status:     change.Status,
+			Similarity: change.Similarity,
+			Additions:  change.Additions,
+			Deletions:  change.Deletions,
+			Binary:     change.Binary,
+		})
+	}
+
+	return entry
+}
+
+func reportMergedPRs(groups []MergeGroup, identities *IdentityResolver) []report.MergedPR {
+	var prs []report.MergedPR
+	for _, group := range groups {
+		prs = append(prs, report.MergedPR{
+			Merge:   reportCommit(group.Merge, identities),
+			Commits: reportCommits(group.Commits, identities),
+		})
+	}
+	return prs
+}
+
+// reportRange converts the ranges logged. With several refs, the range of the
+// report is that of the first ref logged.
+func reportRange(ranges []*LogOptions, refRanges []RefRange) report.Range {
+	var r report.Range
+	if len(ranges) > 0 {
+		r.LogRange = reportLogRange(*ranges[0])
+	}
+	r.Refs = reportRefs(refRanges)
+	return r
+}
+
+func reportLogRange(opts LogOptions) report.LogRange {
+	return report.LogRange{
+		Strategy:   opts.Strategy,
+		Old:        opts.Old,
+		New:        opts.New,
+		ExcludeOld: opts.ExcludeOld,
+	}
+}
+
+func reportRefs(refRanges []RefRange) []report.RefUpdate {
+	var refs []report.RefUpdate
+	for _, refRange := range refRanges {
+		change := refRange.Change
+		ref := report.RefUpdate{
+			Ref:     change.Ref,
+			Name:    change.Name,
+			Type:    change.Type,
+			Action:  change.Action(),
+			OldSHA:  change.OldSHA,
+			NewSHA:  change.NewSHA,
+			Release: change.IsRelease(),
+		}
+		if refRange.Options != nil {
+			logRange := reportLogRange(*refRange.Options)
+			ref.Range = &logRange
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// reportPipeline converts the baseline execution, nil when none was found.
+func reportPipeline(pipeline *models.Pipeline) *report.PipelineRef {
+	if pipeline == nil {
+		return nil
+	}
+	return &report.PipelineRef{
+		Name:        pipeline.Name,
+		Status:      pipeline.Status,
+		StartedTime: pipeline.StartedTime,
+		Duration:    pipeline.Duration,
+	}
+}
+
+func reportPerson(person Person) report.Person {
+	return report.Person{Name: person.Name, Email: person.Email}
+}
+
+func reportPeople(people []Person) []report.Person {
+	var result []report.Person
+	for _, person := range people {
+		result = append(result, reportPerson(person))
+	}
+	return result
+}
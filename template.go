@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"commit-insights/internal/report"
+)
+
+// reportTemplateName is the template executed to render a report. In a
+// directory of templates, it is the file named report with any extension,
+// the other files being partials.
+const reportTemplateName = "report"
+
+// htmlTemplateExtensions are the extensions of the templates parsed with
+// html/template, which escapes the report values. Any other file is parsed
+// with text/template.
+var htmlTemplateExtensions = map[string]bool{
+	".html":   true,
+	".htm":    true,
+	".gohtml": true,
+}
+
+// ReportTemplate is a user-supplied report template, replacing the built-in
+// template of the html format when it is an HTML template, and of the text
+// format otherwise.
+type ReportTemplate struct {
+	// Path is the template file or directory.
+	Path string
+	HTML *htmltemplate.Template
+	Text *texttemplate.Template
+}
+
+// LoadReportTemplate parses the template file at path, or the templates of
+// the directory at path: its report file and the partials it includes with
+// {{template "<file name>" .}} or defines with {{define}}.
+func LoadReportTemplate(path string) (*ReportTemplate, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := path
+	var partials []string
+	if info.IsDir() {
+		files, err := filepath.Glob(filepath.Join(path, "*"))
+		if err != nil {
+			return nil, err
+		}
+		entry = ""
+		for _, file := range files {
+			if fileInfo, err := os.Stat(file); err != nil || fileInfo.IsDir() {
+				continue
+			}
+			name := filepath.Base(file)
+			if strings.TrimSuffix(name, filepath.Ext(name)) == reportTemplateName && entry == "" {
+				entry = file
+				continue
+			}
+			partials = append(partials, file)
+		}
+		if entry == "" {
+			return nil, fmt.Errorf("template directory %s has no %s file", path, reportTemplateName)
+		}
+	}
+
+	tmpl := &ReportTemplate{Path: path}
+	if htmlTemplateExtensions[strings.ToLower(filepath.Ext(entry))] {
+		tmpl.HTML = htmltemplate.New(reportTemplateName).Funcs(templateFuncs)
+	} else {
+		tmpl.Text = texttemplate.New(reportTemplateName).Funcs(templateFuncs)
+	}
+
+	// The report file is parsed into the root template, partials are added
+	// to it under their file name.
+	for _, file := range append([]string{entry}, partials...) {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		if tmpl.HTML != nil {
+			t := tmpl.HTML
+			if file != entry {
+				t = t.New(filepath.Base(file))
+			}
+			_, err = t.Parse(string(content))
+		} else {
+			t := tmpl.Text
+			if file != entry {
+				t = t.New(filepath.Base(file))
+			}
+			_, err = t.Parse(string(content))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tmpl, nil
+}
+
+// IsHTML reports whether the template is an html/template.
+func (t *ReportTemplate) IsHTML() bool {
+	return t.HTML != nil
+}
+
+// Execute renders the report template with data.
+func (t *ReportTemplate) Execute(w io.Writer, data interface{}) error {
+	if t.HTML != nil {
+		return t.HTML.ExecuteTemplate(w, reportTemplateName, data)
+	}
+	if t.Text != nil {
+		return t.Text.ExecuteTemplate(w, reportTemplateName, data)
+	}
+	return errors.New("empty report template")
+}
+
+// templateFuncs are the helper functions available in report templates.
+var templateFuncs = map[string]interface{}{
+	// shortHash abbreviates a commit hash: {{shortHash .Hash}}
+	"shortHash": report.ShortHash,
+	// join joins a list: {{join .Releases ", "}}
+	"join":  func(items []string, sep string) string { return strings.Join(items, sep) },
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	// replace replaces every occurrence of old: {{replace .Title "_" " "}}
+	"replace":   func(s string, old string, new string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":  strings.Contains,
+	"hasPrefix": strings.HasPrefix,
+	"hasSuffix": strings.HasSuffix,
+	// truncate shortens a text to n characters: {{truncate 50 .Title}}
+	"truncate": func(n int, s string) string {
+		runes := []rune(s)
+		if n < 0 || len(runes) <= n {
+			return s
+		}
+		return string(runes[:n]) + "…"
+	},
+	// default returns the value, or fallback when it is empty: {{default "-" .Reviewer}}
+	"default": func(fallback string, value string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	},
+	// plural counts a noun: {{plural .Insights.Stats.Commits "commit"}}
+	"plural": plural,
+	"add":    func(a int, b int) int { return a + b },
+	"sub":    func(a int, b int) int { return a - b },
+	// statusEmoji marks a git status letter, as in the Markdown report.
+	"statusEmoji": statusEmoji,
+	// formatTime formats an RFC 3339 time, e.g. the commit times of
+	// .Insights: {{formatTime "Jan 02 15:04" .Time}}
+	"formatTime": func(layout string, value string) string {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return value
+		}
+		return parsed.Local().Format(layout)
+	},
+	// toJSON encodes a value as JSON, e.g. for scripts: {{toJSON .Insights.Stats}}
+	"toJSON": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
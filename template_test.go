@@ -0,0 +1,174 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"commit-insights/internal/report"
+)
+
+// templateReport is a report whose repository name must be escaped in HTML.
+func templateReport() *report.Report {
+	return &report.Report{
+		Repository: report.Repository{Name: "<acme>", Branch: "main", BuildType: "push"},
+		Commits:    []report.CommitEntry{{Hash: "9049f1265b7d61be4a8904a9a27120d2064dab3b", Title: "Add feature"}},
+	}
+}
+
+func TestLoadReportTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are written to the template directory, path is the template
+		// setting relative to it.
+		files map[string]string
+		path  string
+		html  bool
+		// want is in the report of the format the template replaces.
+		want []string
+	}{
+		{
+			name:  "html file",
+			files: map[string]string{"custom.html": "<h1>{{.RepoName}}</h1>"},
+			path:  "custom.html",
+			html:  true,
+			want:  []string{"<h1>&lt;acme&gt;</h1>"},
+		},
+		{
+			name:  "uppercase gohtml extension",
+			files: map[string]string{"custom.GOHTML": "<h1>{{.RepoName}}</h1>"},
+			path:  "custom.GOHTML",
+			html:  true,
+			want:  []string{"<h1>&lt;acme&gt;</h1>"},
+		},
+		{
+			name:  "text file",
+			files: map[string]string{"custom.md": "# {{.RepoName}} ({{plural .Insights.Stats.Commits \"commit\"}})"},
+			path:  "custom.md",
+			want:  []string{"# <acme> (1 commit)"},
+		},
+		{
+			name: "html directory with partials",
+			files: map[string]string{
+				"report.html": `{{template "header.html" .}}<p>{{template "footer" .}}</p>`,
+				"header.html": "<h1>{{.RepoName}}</h1>",
+				"footer.tmpl": `{{define "footer"}}{{.BranchName}}{{end}}`,
+			},
+			html: true,
+			want: []string{"<h1>&lt;acme&gt;</h1>", "<p>main</p>"},
+		},
+		{
+			name: "text directory with partials",
+			files: map[string]string{
+				"report.txt":  `{{range .Report.Commits}}{{template "commit.txt" .}}{{end}}`,
+				"commit.txt":  "{{shortHash .Hash}} {{.Title}}\n",
+				"readme.html": "<p>A partial, the engine is chosen by the report file</p>",
+			},
+			want: []string{"9049f12 Add feature\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, filepath.Join(dir, name), content)
+			}
+			path := filepath.Join(dir, tt.path)
+
+			tmpl, err := LoadReportTemplate(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tmpl.IsHTML() != tt.html {
+				t.Errorf("got IsHTML() %v, want %v", tmpl.IsHTML(), tt.html)
+			}
+
+			// The template replaces the report of its format only.
+			opts := RenderOptions{Template: tmpl}
+			html, err := htmlRenderer{}.Render(templateReport(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, err := textRenderer{}.Render(templateReport(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			custom, builtin := string(text), string(html)
+			if tt.html {
+				custom, builtin = builtin, custom
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(custom, want) {
+					t.Errorf("report does not contain %q:\n%s", want, custom)
+				}
+			}
+			if !isBuiltinReport(builtin) {
+				t.Errorf("the report of the other format is not the built-in one:\n%s", builtin)
+			}
+		})
+	}
+}
+
+func TestLoadReportTemplateErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "broken.html"), "{{if .RepoName}}")
+	partials := filepath.Join(dir, "partials")
+	if err := os.Mkdir(partials, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(partials, "header.html"), "<h1>{{.RepoName}}</h1>")
+
+	for _, path := range []string{
+		filepath.Join(dir, "missing.html"),
+		filepath.Join(dir, "broken.html"),
+		partials,
+	} {
+		if _, err := LoadReportTemplate(path); err == nil {
+			t.Errorf("LoadReportTemplate(%s) succeeded, want an error", path)
+		}
+	}
+}
+
+func TestReportTemplateFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{name: "html missing field", file: "report.html", content: "<h1>{{.Missing}}</h1>"},
+		{name: "html missing partial", file: "report.html", content: `{{template "header.html" .}}`},
+		{name: "text missing field", file: "report.txt", content: "{{.Missing}}"},
+		{name: "text failing function", file: "report.txt", content: "{{toJSON .Report.Dashboard}}{{index .Releases 3}}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			writeFile(t, path, tt.content)
+			tmpl, err := LoadReportTemplate(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var renderer Renderer = textRenderer{}
+			if tmpl.IsHTML() {
+				renderer = htmlRenderer{}
+			}
+			got, err := renderer.Render(templateReport(), RenderOptions{Template: tmpl})
+			if err != nil {
+				t.Fatalf("the report was not rendered with the built-in template: %v", err)
+			}
+			if !isBuiltinReport(string(got)) {
+				t.Errorf("got a report without the built-in template:\n%s", got)
+			}
+		})
+	}
+}
+
+// isBuiltinReport reports whether an html or text report was rendered with
+// the built-in template.
+func isBuiltinReport(output string) bool {
+	return strings.Contains(output, "Repository Name:") || strings.HasPrefix(output, "Commit Insights: <acme>")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// Person is a name and email pair, as found in commit trailers.
+type Person struct {
+	Name  string
+	Email string
+}
+
+// Trailer keys extracted from commit messages, lower cased.
+const (
+	trailerCoAuthoredBy = "co-authored-by"
+	trailerReviewedBy   = "reviewed-by"
+	trailerSignedOffBy  = "signed-off-by"
+)
+
+var trailerRegex = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*)\s*:\s*(.*)$`)
+
+// parseTrailers returns the trailers of a commit body, keyed by lower cased
+// trailer name. Like git interpret-trailers, trailers are only looked for in
+// the last paragraph, and only if every line of it is a trailer (or the
+// continuation of one).
+func parseTrailers(body string) map[string][]string {
+	body = strings.TrimRight(strings.ReplaceAll(body, "\r\n", "\n"), "\n ")
+	if body == "" {
+		return nil
+	}
+
+	paragraph := body
+	if i := strings.LastIndex(body, "\n\n"); i >= 0 {
+		paragraph = body[i+2:]
+	}
+
+	trailers := make(map[string][]string)
+	var lastKey string
+	for _, line := range strings.Split(paragraph, "\n") {
+		if lastKey != "" && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			values := trailers[lastKey]
+			values[len(values)-1] += " " + strings.TrimSpace(line)
+			continue
+		}
+		match := trailerRegex.FindStringSubmatch(line)
+		if match == nil {
+			return nil
+		}
+		lastKey = strings.ToLower(match[1])
+		trailers[lastKey] = append(trailers[lastKey], strings.TrimSpace(match[2]))
+	}
+
+	return trailers
+}
+
+// parsePerson parses a "Name <email>" trailer value. Values without an
+// address are kept as a name only.
+func parsePerson(value string) Person {
+	if address, err := mail.ParseAddress(value); err == nil {
+		return Person{Name: address.Name, Email: address.Address}
+	}
+	if name, email, ok := strings.Cut(value, "<"); ok {
+		return Person{Name: strings.TrimSpace(name), Email: strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(email), ">"))}
+	}
+	return Person{Name: strings.TrimSpace(value)}
+}
+
+// parsePeople parses every value of a trailer into a Person.
+func parsePeople(values []string) []Person {
+	var people []Person
+	for _, value := range values {
+		if person := parsePerson(value); person.Name != "" || person.Email != "" {
+			people = append(people, person)
+		}
+	}
+	return people
+}
+
+// applyTrailers fills the trailer fields of a commit from its body.
+func applyTrailers(commit *CommitInfo) {
+	trailers := parseTrailers(commit.Body)
+	commit.CoAuthors = parsePeople(trailers[trailerCoAuthoredBy])
+	commit.ReviewedBy = parsePeople(trailers[trailerReviewedBy])
+	commit.SignedOffBy = parsePeople(trailers[trailerSignedOffBy])
+}
+
+// displayNames returns the names of people, falling back to their email.
+func displayNames(people []Person) []string {
+	var names []string
+	for _, person := range people {
+		if person.Name != "" {
+			names = append(names, person.Name)
+		} else {
+			names = append(names, person.Email)
+		}
+	}
+	return names
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTrailers(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want map[string][]string
+	}{
+		{
+			name: "last paragraph only",
+			body: "Reviewed-by: not a trailer paragraph\nsince this line is prose.\n\nCo-authored-by: Ada Lovelace <ada@example.com>\nSigned-off-by: Grace Hopper <grace@example.com>\n",
+			want: map[string][]string{
+				trailerCoAuthoredBy: {"Ada Lovelace <ada@example.com>"},
+				trailerSignedOffBy:  {"Grace Hopper <grace@example.com>"},
+			},
+		},
+		{
+			name: "trailers only body",
+			body: "Signed-off-by: Ada Lovelace <ada@example.com>",
+			want: map[string][]string{trailerSignedOffBy: {"Ada Lovelace <ada@example.com>"}},
+		},
+		{
+			name: "prose in the last paragraph",
+			body: "Fix the parser.\n\nCo-authored-by: Ada Lovelace <ada@example.com>\nThanks for the help!",
+		},
+		{
+			name: "continuation lines",
+			body: "Body.\n\nReviewed-by: Ada Lovelace\n  <ada@example.com>\nCo-authored-by: Grace Hopper\n\t<grace@example.com>",
+			want: map[string][]string{
+				trailerReviewedBy:   {"Ada Lovelace <ada@example.com>"},
+				trailerCoAuthoredBy: {"Grace Hopper <grace@example.com>"},
+			},
+		},
+		{
+			name: "continuation without a trailer",
+			body: "Body.\n\n  <ada@example.com>\nReviewed-by: Ada Lovelace",
+		},
+		{
+			name: "case-insensitive keys",
+			body: "Body.\n\nCO-AUTHORED-BY: Ada Lovelace <ada@example.com>\nco-authored-by: Grace Hopper <grace@example.com>\nReviewed-By : Alan Turing <alan@example.com>",
+			want: map[string][]string{
+				trailerCoAuthoredBy: {"Ada Lovelace <ada@example.com>", "Grace Hopper <grace@example.com>"},
+				trailerReviewedBy:   {"Alan Turing <alan@example.com>"},
+			},
+		},
+		{
+			name: "CRLF line endings and trailing blank lines",
+			body: "Body.\r\n\r\nSigned-off-by: Ada Lovelace <ada@example.com>\r\n\r\n",
+			want: map[string][]string{trailerSignedOffBy: {"Ada Lovelace <ada@example.com>"}},
+		},
+		{
+			name: "no trailers",
+			body: "Rewrite the parser.\n\nThe old one could not handle semicolons.",
+		},
+		{
+			name: "empty body",
+			body: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTrailers(tt.body)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTrailers(%q) = %q, want %q", tt.body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePerson(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Person
+	}{
+		{"Ada Lovelace <ada@example.com>", Person{Name: "Ada Lovelace", Email: "ada@example.com"}},
+		{`"Lovelace, Ada" <ada@example.com>`, Person{Name: "Lovelace, Ada", Email: "ada@example.com"}},
+		{"<ada@example.com>", Person{Email: "ada@example.com"}},
+		{"ada@example.com", Person{Email: "ada@example.com"}},
+		{"Ada Lovelace", Person{Name: "Ada Lovelace"}},
+		{"  Ada Lovelace  ", Person{Name: "Ada Lovelace"}},
+		// Malformed addresses are kept as well as possible.
+		{"Ada Lovelace <ada@example.com", Person{Name: "Ada Lovelace", Email: "ada@example.com"}},
+		{"Ada Lovelace <not an address>", Person{Name: "Ada Lovelace", Email: "not an address"}},
+		{"", Person{}},
+	}
+
+	for _, tt := range tests {
+		if got := parsePerson(tt.value); got != tt.want {
+			t.Errorf("parsePerson(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+
+	people := parsePeople([]string{"Ada Lovelace <ada@example.com>", " ", "Grace Hopper"})
+	want := []Person{{Name: "Ada Lovelace", Email: "ada@example.com"}, {Name: "Grace Hopper"}}
+	if !reflect.DeepEqual(people, want) {
+		t.Errorf("parsePeople() = %+v, want %+v", people, want)
+	}
+}